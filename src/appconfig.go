@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Wraps the errors of reading the --app-config store, which happen at startup, before any connection is made.
+var ErrAppConfig = errors.New("could not read settings from Azure App Configuration")
+
+// Load the key-values named like the environment variables, like WAKEUP_SERVER, from an App Configuration store
+// into the environment, like an env file: variables that are already set are not overwritten.
+// Only key-values without a label are read.
+func LoadAppConfig(ctx context.Context, credential azcore.TokenCredential, endpoint string) error {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: expected an endpoint like https://<store>.azconfig.io, got '%s'", ErrAppConfig, endpoint)
+	}
+	u.Path = "/kv"
+	u.RawQuery = url.Values{"key": {envPrefix + "_*"}, "label": {"\x00"}, "api-version": {"1.0"}}.Encode()
+
+	// The scope follows the store's cloud, like azconfig.io or azconfig.azure.cn
+	_, domain, _ := strings.Cut(u.Hostname(), ".")
+	scope := "https://" + domain + "/.default"
+
+	// Results are paged, with a link to the next page relative to the store
+	for next := u.String(); next != ""; {
+		body, err := callAzureAPI(ctx, credential, "App Configuration", scope, http.MethodGet, next)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAppConfig, err)
+		}
+
+		var page struct {
+			Items []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"items"`
+			NextLink string `json:"@nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("%w: %v", ErrAppConfig, err)
+		}
+
+		for _, item := range page.Items {
+			if _, exists := os.LookupEnv(item.Key); !exists {
+				os.Setenv(item.Key, item.Value)
+				loadedEnv[item.Key] = "app-config"
+			}
+		}
+
+		next = ""
+		if page.NextLink != "" {
+			link, err := u.Parse(page.NextLink)
+			if err != nil {
+				return fmt.Errorf("%w: invalid next link '%s'", ErrAppConfig, page.NextLink)
+			}
+			next = link.String()
+		}
+	}
+	return nil
+}
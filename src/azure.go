@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+const managementEndpoint = "https://management.azure.com"
+
+// Identifies a database as an Azure resource.
+type AzureDatabase struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Server         string // Logical server name, without .database.windows.net
+	Database       string
+}
+
+// Resource ID of the database, for the Azure management API.
+func (d AzureDatabase) ResourceID() string {
+	return d.ServerID() + "/databases/" + url.PathEscape(d.Database)
+}
+
+// Resource ID of the logical server of the database, for the Azure management API.
+func (d AzureDatabase) ServerID() string {
+	return fmt.Sprintf(
+		"/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Sql/servers/%s",
+		url.PathEscape(d.SubscriptionID),
+		url.PathEscape(d.ResourceGroup),
+		url.PathEscape(d.Server),
+	)
+}
+
+// Logical server name of an Azure SQL hostname, like "myserver" for "myserver.database.windows.net".
+func AzureServerName(host string) string {
+	name, _, _ := strings.Cut(host, ".")
+	return name
+}
+
+// Create the DefaultAzureCredential chain: environment, workload identity, managed identity, Azure CLI and Azure Developer CLI.
+// A client ID selects the user-assigned managed identity, without affecting the other credentials of the chain.
+func NewAzureCredential(clientID string) (azcore.TokenCredential, error) {
+	if clientID == "" {
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+
+	// The same chain, as DefaultAzureCredential can only take the managed identity from AZURE_CLIENT_ID
+	var chain []azcore.TokenCredential
+	if c, err := azidentity.NewEnvironmentCredential(nil); err == nil {
+		chain = append(chain, c)
+	}
+	if c, err := azidentity.NewWorkloadIdentityCredential(nil); err == nil {
+		chain = append(chain, c)
+	}
+	mi, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{ID: azidentity.ClientID(clientID)})
+	if err != nil {
+		return nil, err
+	}
+	chain = append(chain, mi)
+	if c, err := azidentity.NewAzureCLICredential(nil); err == nil {
+		chain = append(chain, c)
+	}
+	if c, err := azidentity.NewAzureDeveloperCLICredential(nil); err == nil {
+		chain = append(chain, c)
+	}
+	return azidentity.NewChainedTokenCredential(chain, nil)
+}
+
+// Call the Azure management API, and return the response body if the call was successful.
+func callManagementAPI(ctx context.Context, credential azcore.TokenCredential, method, path string) ([]byte, error) {
+	return callAzureAPI(ctx, credential, "Azure management API", managementEndpoint+"/.default", method, managementEndpoint+path)
+}
+
+// Call an Azure API with a bearer token for scope, and return the response body if the call was successful.
+// The name of the API is used in errors.
+func callAzureAPI(ctx context.Context, credential azcore.TokenCredential, name, scope, method, url string) ([]byte, error) {
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s token: %v", name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+
+	// Like the azidentity token requests, this honors HTTPS_PROXY and NO_PROXY (but not the SOCKS5 --proxy)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling %s: %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s response: %v", name, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s: %s", name, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+// The properties of a database resource that are used by this tool.
+type azureDatabaseResource struct {
+	Kind       string `json:"kind"` // Like "v12.0,user,vcore,serverless"
+	Properties struct {
+		Status string `json:"status"`
+	} `json:"properties"`
+}
+
+// Read a database resource from the Azure management API.
+func readAzureDatabase(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase) (azureDatabaseResource, error) {
+	var resource azureDatabaseResource
+	body, err := callManagementAPI(ctx, credential, http.MethodGet, db.ResourceID()+"?api-version=2021-11-01")
+	if err != nil {
+		return resource, err
+	}
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return resource, fmt.Errorf("error reading Azure management API response: %v", err)
+	}
+	return resource, nil
+}
+
+// Read the status of a database from the Azure management API, like "Online" or "Paused". Does not resume it.
+func AzureDatabaseStatus(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase) (string, error) {
+	resource, err := readAzureDatabase(ctx, credential, db)
+	return resource.Properties.Status, err
+}
+
+// Whether a database is on the serverless compute tier, so it pauses automatically when idle.
+func IsServerlessDatabase(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase) (bool, error) {
+	resource, err := readAzureDatabase(ctx, credential, db)
+	return slices.Contains(strings.Split(resource.Kind, ","), "serverless"), err
+}
+
+// Ask the Azure management API to resume a paused database.
+// This does not wait until the database is resumed: the TDS connection attempts do.
+func ResumeViaAPI(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase) error {
+	_, err := callManagementAPI(ctx, credential, http.MethodPost, db.ResourceID()+"/resume?api-version=2021-11-01")
+	return err
+}
+
+// List the user databases of the logical server of db (its Database is ignored) with the Azure management API.
+// The master database is left out, as a login to it does not resume anything.
+func ListAzureDatabases(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase) ([]string, error) {
+	var names []string
+	next := managementEndpoint + db.ServerID() + "/databases?api-version=2021-11-01"
+	for next != "" {
+		body, err := callAzureAPI(ctx, credential, "Azure management API", managementEndpoint+"/.default", http.MethodGet, next)
+		if err != nil {
+			return nil, err
+		}
+
+		// Large servers return the list in pages
+		var page struct {
+			Value []struct {
+				Name string `json:"name"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("error reading Azure management API response: %v", err)
+		}
+		for _, database := range page.Value {
+			if !strings.EqualFold(database.Name, "master") {
+				names = append(names, database.Name)
+			}
+		}
+		next = page.NextLink
+	}
+	return names, nil
+}
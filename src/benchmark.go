@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// How often the status is read while waiting for the idle pause of a database.
+const pausePollInterval = time.Minute
+
+// One wake-up of a benchmark.
+type BenchmarkIteration struct {
+	Iteration    int     `json:"iteration"`
+	StatusBefore string  `json:"status_before,omitempty"` // From the Azure management API, like "Paused"
+	Success      bool    `json:"success"`
+	Attempts     int     `json:"attempts"`
+	ElapsedMs    int64   `json:"elapsed_ms"`
+	AlreadyAwake bool    `json:"already_awake"`
+	Error        *string `json:"error"`
+}
+
+// The distribution of the wake-up times of the successful iterations, in milliseconds.
+type LatencyStats struct {
+	Count    int   `json:"count"`
+	MinMs    int64 `json:"min_ms"`
+	MedianMs int64 `json:"median_ms"`
+	P90Ms    int64 `json:"p90_ms"`
+	MaxMs    int64 `json:"max_ms"`
+	MeanMs   int64 `json:"mean_ms"`
+}
+
+// The result document of the benchmark command.
+type BenchmarkResult struct {
+	Server     string               `json:"server"`
+	Database   string               `json:"database"`
+	Iterations []BenchmarkIteration `json:"iterations"`
+	Latency    *LatencyStats        `json:"latency"` // Null if no iteration succeeded
+}
+
+// Compute the distribution of the successful iterations, with nearest-rank percentiles.
+func NewLatencyStats(iterations []BenchmarkIteration) *LatencyStats {
+	var elapsed []int64
+	var total int64
+	for _, it := range iterations {
+		if it.Success {
+			elapsed = append(elapsed, it.ElapsedMs)
+			total += it.ElapsedMs
+		}
+	}
+	if len(elapsed) == 0 {
+		return nil
+	}
+
+	slices.Sort(elapsed)
+	rank := func(p int) int64 {
+		return elapsed[(p*len(elapsed)+99)/100-1]
+	}
+	return &LatencyStats{
+		Count:    len(elapsed),
+		MinMs:    elapsed[0],
+		MedianMs: rank(50),
+		P90Ms:    rank(90),
+		MaxMs:    elapsed[len(elapsed)-1],
+		MeanMs:   total / int64(len(elapsed)),
+	}
+}
+
+// Poll the Azure management API until the database is paused, and return its status.
+func WaitForPause(ctx context.Context, credential azcore.TokenCredential, db AzureDatabase, logger *slog.Logger) (string, error) {
+	for logged := false; ; logged = true {
+		status, err := AzureDatabaseStatus(ctx, credential, db)
+		if err != nil {
+			return "", err
+		}
+		if status == "Paused" {
+			return status, nil
+		}
+		if !logged {
+			logger.Info(fmt.Sprintf("Database '%s' is %s, waiting for its idle pause.", db.Database, status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("database '%s' did not pause in time: %w", db.Database, ctx.Err())
+		case <-time.After(pausePollInterval):
+		}
+	}
+}
+
+// Write the benchmark result as JSON.
+func WriteBenchmarkResult(w io.Writer, result BenchmarkResult) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+// Measure how long a serverless database takes to resume, over a number of wake-ups. For development only.
+func runBenchmark(args []string) int {
+	fs := newFlagSet("benchmark", `Measure the cold-start time of a serverless database, over a number of wake-ups. For development only.
+
+  Each iteration optionally waits for the idle pause of the database (with the Azure management API), and then wakes it up.
+  The wake-up times are printed as JSON. As the database repeatedly pauses and resumes, --confirm-database must repeat its name.`)
+	conn := addConnectionFlags(fs)
+	out := addOutputFlags(fs)
+	attempt := addAttemptFlags(fs)
+	iterations := fs.Int("iterations", GetEnvInt(WAKEUP_ITERATIONS, 3), "Number of wake-ups to measure")
+	waitForPause := fs.Bool("wait-for-pause", GetEnvBool(WAKEUP_WAIT_FOR_PAUSE, false), "Before each wake-up, wait until the database paused when idle (needs --subscription-id and --resource-group)")
+	pauseTimeout := fs.Duration("pause-timeout", GetEnvDuration(WAKEUP_PAUSE_TIMEOUT, 2*time.Hour), "Maximum time to wait for each idle pause")
+	// Deliberately without an environment variable, so it cannot be left in a shared configuration
+	confirmDatabase := fs.String("confirm-database", "", "Name of the database, to confirm that it may be paused and resumed")
+
+	parseFlags(fs, out, args)
+
+	logger, err := out.logger()
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	logger, err = conn.withCorrelationID(logger)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	if *iterations < 1 {
+		log.Fatalf("error: --iterations must be at least 1")
+	}
+
+	configs, err := attempt.configs(conn, logger)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	if len(configs) != 1 || len(configs[0].Fallbacks) > 0 {
+		log.Fatalf("error: benchmark measures a single database, without fallback servers")
+	}
+	config := configs[0]
+	config.Trace = *out.trace
+	database := Check(config).Database
+	if database == "" || *confirmDatabase != database {
+		log.Fatalf("error: benchmark repeatedly pauses and resumes database '%s', confirm it with --confirm-database=%s", database, database)
+	}
+
+	var credential azcore.TokenCredential
+	var resource AzureDatabase
+	if *conn.subscriptionID != "" || *waitForPause {
+		if resource, err = conn.azureDatabase(config); err == nil {
+			credential, err = conn.credential()
+		}
+		if err != nil {
+			log.Fatalf("error: benchmark needs the Azure database to read its status: %v", err)
+		}
+
+		// A provisioned database never pauses, and is more likely to serve production
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		serverless, err := IsServerlessDatabase(ctx, credential, resource)
+		cancel()
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		if !serverless {
+			log.Fatalf("error: database '%s' is not serverless, so it cannot be benchmarked", database)
+		}
+	}
+
+	result := BenchmarkResult{Server: Check(config).Server, Database: database}
+	failed := false
+	for i := 1; i <= *iterations; i++ {
+		it := BenchmarkIteration{Iteration: i}
+		if credential != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), *pauseTimeout)
+			if *waitForPause {
+				it.StatusBefore, err = WaitForPause(ctx, credential, resource, logger)
+			} else {
+				it.StatusBefore, err = AzureDatabaseStatus(ctx, credential, resource)
+			}
+			cancel()
+			if err != nil && *waitForPause {
+				// Another wake-up would not be a cold start
+				logger.Error(err.Error())
+				msg := err.Error()
+				it.Error = &msg
+				result.Iterations = append(result.Iterations, it)
+				failed = true
+				break
+			} else if err != nil {
+				logger.Warn(fmt.Sprintf("error reading the status of database '%s': %v", database, err))
+			}
+		}
+
+		logger.Info(fmt.Sprintf("Iteration %d/%d: waking up database '%s'.", i, *iterations, database))
+		r := Wakeup(config)
+		it.Success, it.Attempts, it.ElapsedMs, it.AlreadyAwake, it.Error = r.Success, r.Attempts, r.ElapsedMs, r.AlreadyAwake, r.Error
+		if !r.Success {
+			logger.Error(*r.Error, r.sqlErrorAttrs()...)
+			failed = true
+		}
+		result.Iterations = append(result.Iterations, it)
+	}
+	result.Latency = NewLatencyStats(result.Iterations)
+
+	if err := WriteBenchmarkResult(os.Stdout, result); err != nil {
+		log.Fatalln(err)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
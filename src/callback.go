@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Maximum time of the --on-wake-url request.
+const callbackTimeout = 30 * time.Second
+
+// An HTTP request made once a database is awake, to resume a service that depends on it.
+// The URL, header values and body are templates, with the placeholders of --success-message and {env:NAME}.
+type WakeCallback struct {
+	URL     string
+	Method  string
+	Headers [][2]string // Names and values
+	Body    string
+}
+
+// Validate the options of an --on-wake-url request. The headers are comma-separated, like "Authorization: Bearer {env:TOKEN}".
+func NewWakeCallback(rawURL, method, headers, body string) (*WakeCallback, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --on-wake-url '%s', use an http:// or https:// URL", RedactURL(rawURL))
+	}
+
+	callback := &WakeCallback{URL: rawURL, Method: strings.ToUpper(method), Body: body}
+	for i, header := range strings.Split(headers, ",") {
+		if strings.TrimSpace(header) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			// Not echoed, as it may hold a secret
+			return nil, fmt.Errorf("invalid --on-wake-header entry %d, use Name: value", i+1)
+		}
+		callback.Headers = append(callback.Headers, [2]string{name, strings.TrimSpace(value)})
+	}
+	return callback, nil
+}
+
+// Make the request for an awake database. A response other than 2xx is an error.
+func (c *WakeCallback) Call(ctx context.Context, result Result, logger *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+
+	values := result.templateValues()
+	target := ExpandTemplate(c.URL, values)
+	var body io.Reader
+	if c.Body != "" {
+		body = strings.NewReader(ExpandTemplate(c.Body, values))
+	}
+	req, err := http.NewRequestWithContext(ctx, c.Method, target, body)
+	if err != nil {
+		return fmt.Errorf("invalid request to %s: %v", RedactURL(target), unwrapURLError(err))
+	}
+
+	var logged []string
+	for _, header := range c.Headers {
+		value := ExpandTemplate(header[1], values)
+		req.Header.Add(header[0], value)
+		logged = append(logged, RedactHeader(header[0], value))
+	}
+	if c.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(logged) > 0 {
+		logger.Debug(fmt.Sprintf("Calling %s %s, with headers: %s.", c.Method, RedactURL(target), strings.Join(logged, ", ")))
+	} else {
+		logger.Debug(fmt.Sprintf("Calling %s %s.", c.Method, RedactURL(target)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %v", RedactURL(target), unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", RedactURL(target), resp.Status, strings.TrimSpace(string(text)))
+	}
+	logger.Info(fmt.Sprintf("Called %s %s for database '%s': %s.", c.Method, RedactURL(target), result.Database, resp.Status))
+	return nil
+}
+
+// The error inside a *url.Error, which repeats the URL with its secrets.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
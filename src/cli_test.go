@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Parse the attempt flags of args into a retry policy, like the wakeup command.
+func parseRetry(t *testing.T, args ...string) (RetryConfig, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	attempt := addAttemptFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return attempt.retry()
+}
+
+func TestNoJitter(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want bool
+	}{
+		{"default", "", nil, false},
+		{"flag", "", []string{"--no-jitter"}, true},
+		{"environment variable", "true", nil, true},
+		{"flag over the environment variable", "true", []string{"--no-jitter=false"}, false},
+		{"zero jitter percent", "", []string{"--jitter-percent=0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WAKEUP_NO_JITTER", tt.env)
+			retry, err := parseRetry(t, tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if retry.NoJitter != tt.want {
+				t.Errorf("got NoJitter %t, want %t", retry.NoJitter, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnUnencrypted(t *testing.T) {
+	tests := []struct {
+		name    string
+		encrypt string
+		warned  bool
+	}{
+		{"encryption disabled", ENCRYPT_DISABLE, true},
+		{"login only", ENCRYPT_FALSE, false},
+		{"driver default", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn, err := BuildDSN(ConnectionConfig{Server: "localhost", User: "sa", Password: "secret", Encrypt: tt.encrypt})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var buf bytes.Buffer
+			warnUnencrypted([]Config{{ConnectionString: dsn}}, slog.New(slog.NewTextHandler(&buf, nil)))
+			if warned := strings.Contains(buf.String(), "ENCRYPTION IS DISABLED"); warned != tt.warned {
+				t.Errorf("got warned %v, want %v: %s", warned, tt.warned, buf.String())
+			}
+		})
+	}
+}
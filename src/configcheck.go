@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// The environment variable of each option that has one. Options without one, like --verbose, are only set by flag.
+var optionEnvKeys = map[string]string{
+	"abort-on":                WAKEUP_ABORT_ON,
+	"alert-if-paused":         WAKEUP_ALERT_IF_PAUSED,
+	"app-config":              WAKEUP_APP_CONFIG,
+	"app-name-template":       WAKEUP_APP_NAME_TEMPLATE,
+	"attempt-timeout":         WAKEUP_ATTEMPT_TIMEOUT,
+	"auth":                    WAKEUP_AUTH,
+	"azure-server":            WAKEUP_AZURE_SERVER,
+	"client-id":               WAKEUP_CLIENT_ID,
+	"cooldown":                WAKEUP_COOLDOWN,
+	"correlation-id":          WAKEUP_CORRELATION_ID,
+	"database":                WAKEUP_DATABASE,
+	"dial-timeout":            WAKEUP_DIAL_TIMEOUT,
+	"disable-driver-retry":    WAKEUP_DISABLE_DRIVER_RETRY,
+	"discover-databases":      WAKEUP_DISCOVER_DATABASES,
+	"driver-option":           WAKEUP_DRIVER_OPTIONS,
+	"dsn":                     WAKEUP_DSN,
+	"encrypt":                 WAKEUP_ENCRYPT,
+	"endpoint-type":           WAKEUP_ENDPOINT_TYPE,
+	"env-file":                WAKEUP_ENV_FILE,
+	"env-prefix":              WAKEUP_ENV_PREFIX,
+	"event-socket":            WAKEUP_EVENT_SOCKET,
+	"exec-on-wake":            WAKEUP_EXEC_ON_WAKE,
+	"fail-fast":               WAKEUP_FAIL_FAST,
+	"initial-catalog":         WAKEUP_INITIAL_CATALOG,
+	"instance":                WAKEUP_INSTANCE,
+	"iterations":              WAKEUP_ITERATIONS,
+	"jitter-percent":          WAKEUP_JITTER_PERCENT,
+	"listen":                  WAKEUP_LISTEN,
+	"local-addr":              WAKEUP_LOCAL_ADDR,
+	"lock-file":               WAKEUP_LOCK_FILE,
+	"lock-mode":               WAKEUP_LOCK_MODE,
+	"max-elapsed":             WAKEUP_MAX_ELAPSED,
+	"max-login-rate":          WAKEUP_MAX_LOGIN_RATE,
+	"metrics":                 WAKEUP_METRICS,
+	"min-vcores":              WAKEUP_MIN_VCORES,
+	"multi-subnet-failover":   WAKEUP_MULTI_SUBNET_FAILOVER,
+	"no-jitter":               WAKEUP_NO_JITTER,
+	"no-lint":                 WAKEUP_NO_LINT,
+	"on-wake-body":            WAKEUP_ON_WAKE_BODY,
+	"on-wake-exit-code":       WAKEUP_ON_WAKE_EXIT_CODE,
+	"on-wake-header":          WAKEUP_ON_WAKE_HEADER,
+	"on-wake-method":          WAKEUP_ON_WAKE_METHOD,
+	"on-wake-url":             WAKEUP_ON_WAKE_URL,
+	"output":                  WAKEUP_OUTPUT,
+	"params":                  WAKEUP_PARAMS,
+	"password":                WAKEUP_PASSWORD,
+	"password-keyvault":       WAKEUP_PASSWORD_KEYVAULT,
+	"pause-timeout":           WAKEUP_PAUSE_TIMEOUT,
+	"ping-query":              WAKEUP_PING_QUERY,
+	"port":                    WAKEUP_PORT,
+	"print-connection-string": WAKEUP_PRINT_CONNECTION_STRING,
+	"print-schedule":          WAKEUP_PRINT_SCHEDULE,
+	"profile":                 WAKEUP_PROFILE,
+	"progress":                WAKEUP_PROGRESS,
+	"protocol":                WAKEUP_PROTOCOL,
+	"proxy":                   WAKEUP_PROXY,
+	"quiet":                   WAKEUP_QUIET,
+	"read-only":               WAKEUP_READ_ONLY,
+	"readiness-queries":       WAKEUP_READINESS_QUERIES,
+	"report-file":             WAKEUP_REPORT_FILE,
+	"resource-group":          WAKEUP_RESOURCE_GROUP,
+	"resume-via-api":          WAKEUP_RESUME_VIA_API,
+	"secret":                  WAKEUP_SECRET,
+	"server":                  WAKEUP_SERVER,
+	"shared-budget":           WAKEUP_SHARED_BUDGET,
+	"silent-success":          WAKEUP_SILENT_SUCCESS,
+	"startup-jitter":          WAKEUP_STARTUP_JITTER,
+	"state-file":              WAKEUP_STATE_FILE,
+	"subscription-id":         WAKEUP_SUBSCRIPTION_ID,
+	"success-message":         WAKEUP_SUCCESS_MESSAGE,
+	"target":                  WAKEUP_TARGET,
+	"tcp-keepalive":           WAKEUP_TCP_KEEPALIVE,
+	"timeout":                 WAKEUP_TIMEOUT,
+	"timing":                  WAKEUP_TIMING,
+	"tls-min-version":         WAKEUP_TLS_MIN_VERSION,
+	"token-file":              WAKEUP_TOKEN_FILE,
+	"trace":                   WAKEUP_TRACE,
+	"user":                    WAKEUP_USER,
+	"verify-identity":         WAKEUP_VERIFY_IDENTITY,
+	"verify-retries":          WAKEUP_VERIFY_RETRIES,
+	"verify-stable":           WAKEUP_VERIFY_STABLE,
+	"wait-for-online":         WAKEUP_WAIT_FOR_ONLINE,
+	"wait-for-pause":          WAKEUP_WAIT_FOR_PAUSE,
+	"warm-connections":        WAKEUP_WARM_CONNECTIONS,
+	"warm-duration":           WAKEUP_WARM_DURATION,
+}
+
+// Where the value of an option came from: flag, env, file (--env-file), app-config (--app-config) or default.
+func optionSource(fs *flag.FlagSet, name string) string {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	if set {
+		return "flag"
+	}
+
+	key, ok := optionEnvKeys[name]
+	if !ok {
+		return "default"
+	}
+	var value string
+	var exists bool
+	if key == WAKEUP_ENV_PREFIX {
+		value, exists = os.LookupEnv(key) // Never prefixed itself
+	} else {
+		value, exists = lookupEnv(key)
+		key = envName(key)
+	}
+	if !exists || value == "" {
+		return "default"
+	}
+	if source, ok := loadedEnv[key]; ok {
+		return fmt.Sprintf("%s %s", source, key)
+	}
+	return "env " + key
+}
+
+// Mask the secrets in the value of an option: passwords entirely, and the password in a DSN, driver options or proxy URL.
+func redactOption(name, value string) string {
+	switch name {
+	case "password", "secret":
+		if value != "" {
+			return redacted
+		}
+	case "dsn", "params":
+		return RedactConnectionString(value)
+	case "driver-option":
+		var options []string
+		for _, option := range strings.Split(value, ",") {
+			if key, _, found := strings.Cut(option, "="); found && isSecretParam(key) {
+				option = key + "=" + redacted
+			}
+			options = append(options, option)
+		}
+		return strings.Join(options, ",")
+	case "proxy":
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+	case "on-wake-url":
+		return RedactURL(value)
+	case "on-wake-header":
+		var headers []string
+		for _, header := range strings.Split(value, ",") {
+			name, value, _ := strings.Cut(header, ":")
+			headers = append(headers, RedactHeader(strings.TrimSpace(name), strings.TrimSpace(value)))
+		}
+		return strings.Join(headers, ",")
+	}
+	return value
+}
+
+// Write the effective value of every option of a command and where it came from, like "--server=myserver (env WAKEUP_SERVER)".
+func WriteConfigCheck(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "help" || f.Name == "config-check" {
+			return
+		}
+		fmt.Fprintf(w, "--%s=%s (%s)\n", f.Name, redactOption(f.Name, f.Value.String()), optionSource(fs, f.Name))
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Load KEY=value pairs from a dotenv file into the environment. Variables that are already set are not overwritten.
+// Supports # comments, an optional "export " prefix, and single- or double-quoted values.
+//
+// A [name] line starts the section of a profile. The pairs before the first section apply to all profiles,
+// and the pairs in the section of the given profile override them. The sections of other profiles are skipped.
+func LoadEnvFile(path, profile string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening env file: %v", err)
+	}
+	defer file.Close()
+
+	shared, selected := map[string]string{}, map[string]string{}
+	var profiles []string
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, found := strings.CutSuffix(line, "]")
+			if name = strings.TrimSpace(name[1:]); !found || name == "" {
+				return fmt.Errorf("%s:%d: expected [profile]", path, lineNo)
+			}
+			section = name
+			profiles = append(profiles, name)
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return fmt.Errorf("%s:%d: expected KEY=value", path, lineNo)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+
+		switch section {
+		case "":
+			shared[key] = value
+		case profile:
+			selected[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if profile != "" && !slices.Contains(profiles, profile) {
+		if len(profiles) == 0 {
+			return fmt.Errorf("profile '%s' not found in env file '%s', which has no [profile] sections", profile, path)
+		}
+		return fmt.Errorf("profile '%s' not found in env file '%s', use one of: %s", profile, path, strings.Join(profiles, ", "))
+	}
+
+	maps.Copy(shared, selected)
+	for key, value := range shared {
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+			loadedEnv[key] = "file"
+		}
+	}
+	return nil
+}
+
+// Unquote a dotenv value. Unquoted values end at a " #" comment.
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	switch quote := value[0]; quote {
+	case '\'', '"':
+		end := strings.LastIndexByte(value, quote)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated %c quote", quote)
+		}
+		rest := strings.TrimSpace(value[end+1:])
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected text after quoted value")
+		}
+
+		inner := value[1:end]
+		if quote == '"' {
+			inner = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(inner)
+		}
+		return inner, nil
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvValue(t *testing.T) {
+	tests := []struct {
+		name, value, want string
+	}{
+		{"empty", "", ""},
+		{"plain", "myserver", "myserver"},
+		{"comment", "myserver # the primary", "myserver"},
+		{"hash without a space", "pass#word", "pass#word"},
+		{"single quotes", "'a # b'", "a # b"},
+		{"single quotes without escapes", `'a\nb'`, `a\nb`},
+		{"double quotes with escapes", `"say \"hi\"\n\\"`, "say \"hi\"\n\\"},
+		{"quotes and a comment", `"a b" # comment`, "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnvValue(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []string{`"unterminated`, `'a' b`} {
+		if _, err := parseEnvValue(value); err == nil {
+			t.Errorf("expected an error for %s", value)
+		}
+	}
+}
+
+const testEnvFile = `# Shared by all profiles
+WAKEUP_TEST_SERVER=shared.database.windows.net
+export WAKEUP_TEST_USER="app"
+
+[staging]
+WAKEUP_TEST_SERVER=staging.database.windows.net
+
+[production]
+WAKEUP_TEST_SERVER=production.database.windows.net
+WAKEUP_TEST_TIMEOUT=10m
+`
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		preset  map[string]string
+		want    map[string]string
+	}{
+		{"no profile", "", nil, map[string]string{"WAKEUP_TEST_SERVER": "shared.database.windows.net", "WAKEUP_TEST_USER": "app", "WAKEUP_TEST_TIMEOUT": ""}},
+		{"profile", "staging", nil, map[string]string{"WAKEUP_TEST_SERVER": "staging.database.windows.net", "WAKEUP_TEST_USER": "app", "WAKEUP_TEST_TIMEOUT": ""}},
+		{"other profile", "production", nil, map[string]string{"WAKEUP_TEST_SERVER": "production.database.windows.net", "WAKEUP_TEST_TIMEOUT": "10m"}},
+		{"environment over the file", "production", map[string]string{"WAKEUP_TEST_SERVER": "env.database.windows.net"}, map[string]string{"WAKEUP_TEST_SERVER": "env.database.windows.net", "WAKEUP_TEST_TIMEOUT": "10m"}},
+	}
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(testEnvFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unset, and restored after the test
+			for _, key := range []string{"WAKEUP_TEST_SERVER", "WAKEUP_TEST_USER", "WAKEUP_TEST_TIMEOUT"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, value := range tt.preset {
+				t.Setenv(key, value)
+			}
+
+			if err := LoadEnvFile(path, tt.profile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, want := range tt.want {
+				if got := os.Getenv(key); got != want {
+					t.Errorf("got %s='%s', want '%s'", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEnvFileInvalid(t *testing.T) {
+	tests := []struct {
+		name, content, profile, err string
+	}{
+		{"unknown profile", testEnvFile, "test", "use one of: staging, production"},
+		{"profile without sections", "WAKEUP_TEST_SERVER=myserver\n", "staging", "has no [profile] sections"},
+		{"missing =", "WAKEUP_TEST_SERVER\n", "", ":1: expected KEY=value"},
+		{"empty section name", "[ ]\n", "", ":1: expected [profile]"},
+		{"unterminated quote", "\nWAKEUP_TEST_SERVER=\"myserver\n", "", ":2: unterminated \" quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("WAKEUP_TEST_SERVER", "")
+			os.Unsetenv("WAKEUP_TEST_SERVER")
+
+			err := LoadEnvFile(path, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one with '%s'", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// Types of events written to an event socket.
+const (
+	EVENT_ATTEMPT_STARTED string = "attempt_started"
+	EVENT_RETRY_SCHEDULED string = "retry_scheduled"
+	EVENT_SUCCESS         string = "success"
+	EVENT_FAILURE         string = "failure"
+)
+
+// Events that are kept while the socket is unavailable. Older events are dropped first.
+const maxPendingEvents = 100
+
+// An event of a wake-up, written as a line of JSON. The result is only set for success and failure.
+type Event struct {
+	Time          time.Time `json:"time"`
+	Type          string    `json:"type"`
+	CorrelationID string    `json:"correlation_id"`
+	Database      string    `json:"database"`
+	Attempt       int       `json:"attempt,omitempty"`
+	DelayMs       int64     `json:"delay_ms,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Result        *Result   `json:"result,omitempty"`
+}
+
+// Writer of newline-delimited JSON events to a Unix domain socket, for a local process to consume.
+// The socket is (re)connected on demand. While it is unavailable, events are buffered (with a warning), and the oldest are dropped when the buffer is full.
+type EventSocket struct {
+	path          string
+	correlationID string
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	conn    net.Conn
+	pending [][]byte
+	warned  bool // Whether the current outage was logged
+}
+
+func NewEventSocket(path, correlationID string, logger *slog.Logger) *EventSocket {
+	return &EventSocket{path: path, correlationID: correlationID, logger: logger}
+}
+
+// Write an event, with the time and correlation ID filled in. It never fails: problems are logged as warnings.
+func (s *EventSocket) Emit(event Event) {
+	event.Time = time.Now().UTC()
+	event.CorrelationID = s.correlationID
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("error encoding event: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == maxPendingEvents {
+		s.pending = s.pending[1:]
+		s.logger.Debug(fmt.Sprintf("Event socket '%s' is unavailable, dropping the oldest event.", s.path))
+	}
+	s.pending = append(s.pending, append(line, '\n'))
+	s.flush()
+}
+
+// Write the pending events, connecting first if needed. On failure, they stay pending for the next event.
+func (s *EventSocket) flush() {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("unix", s.path, time.Second)
+		if err != nil {
+			s.warn(err)
+			return
+		}
+		s.conn = conn
+	}
+
+	for len(s.pending) > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if _, err := s.conn.Write(s.pending[0]); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			s.warn(err)
+			return
+		}
+		s.pending = s.pending[1:]
+	}
+	s.warned = false
+}
+
+// Log the first failure of an outage, so an absent consumer does not flood the log.
+func (s *EventSocket) warn(err error) {
+	if !s.warned {
+		s.logger.Warn(fmt.Sprintf("event socket '%s' is unavailable, buffering up to %d events: %v", s.path, maxPendingEvents, err))
+		s.warned = true
+	}
+}
+
+// Try to write the pending events one last time, and close the socket.
+func (s *EventSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) > 0 {
+		s.flush()
+	}
+	if len(s.pending) > 0 {
+		s.logger.Warn(fmt.Sprintf("dropping %d events that could not be written to event socket '%s'", len(s.pending), s.path))
+	}
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// Hook the attempt and retry events of a config into the socket.
+// Existing hooks, like the progress indicator's, are still called.
+func (s *EventSocket) Attach(config *Config, database string) {
+	onAttempt := config.Retry.OnAttempt
+	config.Retry.OnAttempt = func(attempt int) {
+		s.Emit(Event{Type: EVENT_ATTEMPT_STARTED, Database: database, Attempt: attempt})
+		if onAttempt != nil {
+			onAttempt(attempt)
+		}
+	}
+	onRetry := config.Retry.OnRetry
+	config.Retry.OnRetry = func(attempt int, delay time.Duration, err error) {
+		s.Emit(Event{Type: EVENT_RETRY_SCHEDULED, Database: database, Attempt: attempt, DelayMs: delay.Milliseconds(), Error: err.Error()})
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+	}
+}
+
+// Write the success or failure event of a wake-up.
+func (s *EventSocket) EmitResult(result Result) {
+	event := Event{Type: EVENT_SUCCESS, Database: result.Database, Attempt: result.Attempts, Result: &result}
+	if !result.Success {
+		event.Type = EVENT_FAILURE
+		if result.Error != nil {
+			event.Error = *result.Error
+		}
+	}
+	s.Emit(event)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventSocketAttach(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("no Unix domain sockets: %v", err)
+	}
+	defer listener.Close()
+
+	// The hooks that were already set, like those of the progress indicator
+	var attempts, retries []int
+	config := Config{Retry: RetryConfig{
+		OnAttempt: func(attempt int) { attempts = append(attempts, attempt) },
+		OnRetry:   func(attempt int, _ time.Duration, _ error) { retries = append(retries, attempt) },
+	}}
+	socket := NewEventSocket(path, "id", discardLogger)
+	socket.Attach(&config, "db")
+
+	config.Retry.OnAttempt(1)
+	config.Retry.OnRetry(2, time.Second, errors.New("unavailable"))
+	config.Retry.OnAttempt(2)
+	socket.Close()
+
+	if len(attempts) != 2 || len(retries) != 1 {
+		t.Errorf("got %d attempt and %d retry calls of the existing hooks, want 2 and 1", len(attempts), len(retries))
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	want := []Event{
+		{Type: EVENT_ATTEMPT_STARTED, Attempt: 1},
+		{Type: EVENT_RETRY_SCHEDULED, Attempt: 2, DelayMs: 1000, Error: "unavailable"},
+		{Type: EVENT_ATTEMPT_STARTED, Attempt: 2},
+	}
+	scanner := bufio.NewScanner(conn)
+	for i, w := range want {
+		if !scanner.Scan() {
+			t.Fatalf("got %d events, want %d", i, len(want))
+		}
+		var got Event
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+			t.Fatalf("invalid event '%s': %v", scanner.Text(), err)
+		}
+		if got.Type != w.Type || got.Attempt != w.Attempt || got.DelayMs != w.DelayMs || got.Error != w.Error || got.Database != "db" || got.CorrelationID != "id" {
+			t.Errorf("event %d: got %+v, want %+v", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Run a shell command after a database woke up, streaming its stdout to stdout (the writer) and its stderr to stderr.
+// The connection details are passed to the command as WAKEUP_SERVER, WAKEUP_DATABASE and WAKEUP_DSN (with the env prefix).
+func ExecOnWake(ctx context.Context, command string, result Result, connectionString string, stdout io.Writer) error {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		envName(WAKEUP_SERVER)+"="+result.Server,
+		envName(WAKEUP_DATABASE)+"="+result.Database,
+		envName(WAKEUP_DSN)+"="+connectionString,
+	)
+
+	return cmd.Run()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// Wraps the errors of reading the --password-keyvault secret: a missing permission or secret is no failed SQL login.
+var ErrKeyVault = errors.New("could not read secret from Key Vault")
+
+// Read a secret from Key Vault by its identifier, like "https://myvault.vault.azure.net/secrets/sql-password".
+// The identifier may end with a version, otherwise the latest version is read.
+func ReadKeyVaultSecret(ctx context.Context, credential azcore.TokenCredential, secretID string) (string, error) {
+	u, err := url.Parse(secretID)
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Path, "/secrets/") {
+		return "", fmt.Errorf("%w: expected a secret like https://<vault>.vault.azure.net/secrets/<name>, got '%s'", ErrKeyVault, secretID)
+	}
+	u.RawQuery = "api-version=7.4"
+
+	// The scope follows the vault's cloud, like vault.azure.net or vault.azure.cn
+	_, domain, _ := strings.Cut(u.Hostname(), ".")
+	body, err := callAzureAPI(ctx, credential, "Key Vault", "https://"+domain+"/.default", http.MethodGet, u.String())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrKeyVault, err)
+	}
+
+	var secret struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrKeyVault, err)
+	}
+	return secret.Value, nil
+}
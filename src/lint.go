@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Hostname suffixes of Azure SQL Database and Synapse, in the public and sovereign clouds.
+var azureSQLSuffixes = []string{
+	".database.windows.net",
+	".database.chinacloudapi.cn",
+	".database.usgovcloudapi.net",
+	".sql.azuresynapse.net",
+}
+
+// Whether a hostname is an Azure SQL logical server or Synapse workspace endpoint.
+func IsAzureSQLHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, suffix := range azureSQLSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check a parsed connection string for common Azure SQL mistakes. Every problem found is returned as a warning:
+// none of them stops the connection, as some are only suspicious.
+func LintConnection(p msdsn.Config) []string {
+	var warnings []string
+	host := strings.ToLower(strings.TrimSuffix(p.Host, "."))
+	if !IsAzureSQLHost(host) {
+		if strings.HasSuffix(host, ".windows.net") || strings.Contains(host, ".database.") {
+			warnings = append(warnings, fmt.Sprintf("server '%s' looks like a misspelled Azure SQL hostname, like <server>.database.windows.net", p.Host))
+		}
+		return warnings
+	}
+
+	server := AzureServerName(host)
+	if p.Encryption == msdsn.EncryptionOff {
+		warnings = append(warnings, fmt.Sprintf("encrypt=false for Azure SQL server '%s': only the login is encrypted, use encrypt=true", p.Host))
+	}
+	if p.TLSConfig != nil && p.TLSConfig.InsecureSkipVerify {
+		warnings = append(warnings, fmt.Sprintf("the certificate of Azure SQL server '%s' is not verified (TrustServerCertificate), which is only needed for local servers", p.Host))
+	}
+	if p.Port != 0 && p.Port != 1433 {
+		warnings = append(warnings, fmt.Sprintf("port %d for Azure SQL server '%s': its gateway only listens on 1433", p.Port, p.Host))
+	}
+	if p.Instance != "" {
+		warnings = append(warnings, fmt.Sprintf("instance '%s' for Azure SQL server '%s': Azure SQL has no named instances", p.Instance, p.Host))
+	}
+	if _, suffix, found := strings.Cut(p.User, "@"); found && !strings.EqualFold(suffix, server) {
+		// The legacy user@server form must name the logical server, and an Entra account cannot use a SQL login
+		warnings = append(warnings, fmt.Sprintf("user '%s' is not in the form user@%s: a SQL login is the plain user name (or user@%s), and a Microsoft Entra account needs --auth=%s", p.User, server, server, AUTH_AZURE_DEFAULT))
+	}
+	return warnings
+}
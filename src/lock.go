@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Ways to handle a lock file that is held by another run.
+const (
+	LOCK_MODE_WAIT string = "wait"
+	LOCK_MODE_EXIT string = "exit"
+)
+
+// Returned when the lock file is held by another run, which is probably waking up the same database.
+var ErrLocked = errors.New("another wake-up is already in progress")
+
+// Take an exclusive lock on path, which is created if needed. If another process holds it,
+// return ErrLocked right away, or with wait, retry every second until the lock is free or wait has passed.
+// Closing the file (or exiting) releases the lock.
+func AcquireLock(path string, wait time.Duration) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %v", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error locking '%s': %v", path, err)
+		}
+		if locked {
+			return f, nil
+		}
+		if !time.Now().Add(time.Second).Before(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: '%s' is locked", ErrLocked, path)
+		}
+		time.Sleep(time.Second)
+	}
+}
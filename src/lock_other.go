@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// File locks are only implemented with flock.
+func tryLock(f *os.File) (bool, error) {
+	return false, errors.ErrUnsupported
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Handler that writes log records like the standard logger: "2025/04/07 17:12:20 INFO message key=value".
+type logHandler struct {
+	logger *log.Logger
+	level  slog.Leveler
+	attrs  []slog.Attr
+	group  string
+}
+
+// Create a logger that writes records at or above level to w.
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&logHandler{logger: log.New(w, "", log.LstdFlags), level: level})
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteString(" ")
+	b.WriteString(r.Message)
+
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	})
+
+	h.logger.Print(b.String())
+	return nil
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	child.attrs = append(child.attrs, h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		child.attrs = append(child.attrs, a)
+	}
+	return &child
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	if child.group != "" {
+		child.group += "." + name
+	} else {
+		child.group = name
+	}
+	return &child
+}
+
+// Categories of the driver log that --trace enables. Rows and query parameters are left out, as they may hold data.
+const traceLogFlags = msdsn.LogErrors | msdsn.LogMessages | msdsn.LogSQL | msdsn.LogTransaction | msdsn.LogDebug | msdsn.LogRetries
+
+// Names of the driver log categories, as logged in the driver attribute.
+var driverLogCategories = map[msdsn.Log]string{
+	msdsn.LogErrors:      "errors",
+	msdsn.LogMessages:    "messages",
+	msdsn.LogRows:        "rows",
+	msdsn.LogSQL:         "sql",
+	msdsn.LogParams:      "params",
+	msdsn.LogTransaction: "transaction",
+	msdsn.LogDebug:       "debug",
+	msdsn.LogRetries:     "retries",
+}
+
+// A mssql.ContextLogger that writes the TDS-level diagnostics of the driver as debug records, with any secret masked.
+type driverLogger struct {
+	logger  *slog.Logger
+	secrets []string
+}
+
+func (d driverLogger) Log(ctx context.Context, category msdsn.Log, msg string) {
+	for _, secret := range d.secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, "REDACTED")
+		}
+	}
+	d.logger.DebugContext(ctx, msg, "driver", driverLogCategories[category])
+}
+
+// Generate a random (version 4) UUID, to correlate the log records of a run.
+func NewCorrelationID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("error generating correlation ID: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		log   func(*slog.Logger)
+		want  string // Without the timestamp, empty if nothing is written
+	}{
+		{"info", slog.LevelInfo, func(l *slog.Logger) { l.Info("Connected.") }, "INFO Connected."},
+		{"attributes", slog.LevelInfo, func(l *slog.Logger) { l.Warn("retrying", "attempt", 2) }, "WARN retrying attempt=2"},
+		{"logger attributes", slog.LevelInfo, func(l *slog.Logger) { l.With("correlation_id", "id").Info("Connected.", "server", "srv") }, "INFO Connected. correlation_id=id server=srv"},
+		{"group", slog.LevelInfo, func(l *slog.Logger) { l.WithGroup("sql").Error("failed", "number", 40613) }, "ERROR failed sql.number=40613"},
+		{"below the level", slog.LevelInfo, func(l *slog.Logger) { l.Debug("detail") }, ""},
+		{"debug level", slog.LevelDebug, func(l *slog.Logger) { l.Debug("detail") }, "DEBUG detail"},
+	}
+	timestamp := regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(NewLogger(&buf, tt.level))
+			got := strings.TrimSuffix(buf.String(), "\n")
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("got '%s', want nothing", got)
+				}
+				return
+			}
+			if !timestamp.MatchString(got) || timestamp.ReplaceAllString(got, "") != tt.want {
+				t.Errorf("got '%s', want a timestamp and '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryLogger(t *testing.T) {
+	// Nothing may be logged to the default logger, so embedders can capture all records
+	defaultLogger := slog.Default()
+	var leaked bytes.Buffer
+	slog.SetDefault(NewLogger(&leaked, slog.LevelDebug))
+	defer slog.SetDefault(defaultLogger)
+
+	var buf bytes.Buffer
+	config := RetryConfig{MaxRetries: 2, RetryDelay: time.Millisecond, NoJitter: true, Logger: NewLogger(&buf, slog.LevelInfo)}
+	config.Do(context.Background(), func() error { return io.EOF })
+
+	if !strings.Contains(buf.String(), "INFO attempt 1/2") || !strings.Contains(buf.String(), "INFO attempt 2/2 after 1ms delay") {
+		t.Errorf("got '%s', want both attempts logged", buf.String())
+	}
+	if leaked.Len() > 0 {
+		t.Errorf("logged to the default logger: %s", leaked.String())
+	}
+}
+
+func TestDriverLoggerRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	d := driverLogger{logger: NewLogger(&buf, slog.LevelDebug), secrets: []string{"hunter2", ""}}
+	d.Log(context.Background(), msdsn.LogSQL, "login with password hunter2")
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "login with password REDACTED driver=sql") {
+		t.Errorf("got '%s', want the secret masked", buf.String())
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, err := NewCorrelationID()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := NewCorrelationID()
+	if !uuid.MatchString(a) || a == b {
+		t.Errorf("got '%s' and '%s', want two different version 4 UUIDs", a, b)
+	}
+}
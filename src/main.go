@@ -2,57 +2,398 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"maps"
+	"math"
 	"math/rand/v2"
+	"net"
 	"net/url"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	_ "github.com/microsoft/go-mssqldb"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+	_ "github.com/microsoft/go-mssqldb/namedpipe"    // Registers the np protocol, on Windows only
+	_ "github.com/microsoft/go-mssqldb/sharedmemory" // Registers the lpc protocol, on Windows only
+	"golang.org/x/time/rate"
 )
 
-// Get environment variable by name. If it does not exist, return a default value.
+// Prefix of all environment variables, resolved at startup.
+var envPrefix = "WAKEUP"
+
+// Name of an environment variable with the configured prefix, like STAGING_WAKEUP_SERVER for WAKEUP_SERVER.
+func envName(key string) string {
+	return envPrefix + strings.TrimPrefix(key, "WAKEUP")
+}
+
+// Environment variables set by --env-file or --app-config, with that source, for --config-check.
+var loadedEnv = map[string]string{}
+
+// Environment variables whose value was sanitized by lookupEnv, to report once logging is set up.
+var sanitizedEnv []string
+
+// Look up an environment variable by name, with the env prefix.
+// Surrounding whitespace and matching quotes are removed, as some CI systems add them.
+func lookupEnv(key string) (string, bool) {
+	raw, exists := os.LookupEnv(envName(key))
+	value := strings.TrimSpace(raw)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	if value != raw && !slices.Contains(sanitizedEnv, envName(key)) {
+		sanitizedEnv = append(sanitizedEnv, envName(key))
+	}
+	return value, exists
+}
+
+// Get environment variable by name. If it does not exist or is empty, return a default value.
 func GetEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
+	if value, exists := lookupEnv(key); exists && value != "" {
 		return value
 	}
 	return defaultValue
 }
 
+// Get boolean environment variable by name. If it does not exist or is not a boolean, return a default value.
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value, exists := lookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// Get duration environment variable by name. If it does not exist or is not a duration, return a default value.
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := lookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// Get integer environment variable by name. If it does not exist or is not an integer, return a default value.
+func GetEnvInt(key string, defaultValue int) int {
+	if value, exists := lookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// Get floating-point environment variable by name. If it does not exist or is not a number, return a default value.
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	if value, exists := lookupEnv(key); exists {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// Scope of the access tokens for logging in to Azure SQL.
+const sqlTokenScope = "https://database.windows.net/.default"
+
+// Session label of the connections, unless configured otherwise.
+const DefaultAppName = "ghcr.io/redmer/azure-wakeup-db"
+
+// Connection details. If DSN is set, it is used as-is and all other values are ignored.
+type ConnectionConfig struct {
+	Server   string
+	Port     string // Defaults to 1433, or to the port of the Instance from the SQL Browser
+	Instance string
+	Database string
+	User     string
+	Password string
+	DSN      string
+	ReadOnly bool // Connect with ApplicationIntent=ReadOnly, e.g. to a read-scale replica
+
+	// Optional: the catalog the login targets, instead of Database
+	InitialCatalog string
+
+	// Optional: session label in sys.dm_exec_sessions. Defaults to DefaultAppName.
+	AppName string
+
+	// Log in with an access token instead, so no user or password is needed
+	TokenAuth bool
+
+	// Set DisableRetry: database/sql does not retry a query that starts on a bad connection
+	DisableDriverRetry bool
+
+	// Optional: extra connection string parameters, which override the ones set from the other fields
+	DriverOptions map[string]string
+
+	// Try all IPs of an availability group listener in parallel. If nil, the driver default is used.
+	MultiSubnetFailover *bool
+
+	// Interval of the TCP keep-alive probes, 0 to disable them. If nil, the driver default is used.
+	TCPKeepAlive *time.Duration
+
+	// Connect to a Synapse serverless SQL pool: encrypted, with an access token. Server may be just the workspace name.
+	SynapseServerless bool
+
+	// Network protocol to connect with. Defaults to PROTOCOL_TCP.
+	Protocol string
+
+	// Maximum time to establish the network connection, apart from the login and queries. Defaults to 5 minutes.
+	DialTimeout time.Duration
+
+	// Optional: encryption mode of the driver, one of the ENCRYPT_* values. Defaults to the driver default.
+	Encrypt string
+
+	// Optional: connection parameters from ParseConnectionParams, which override the other fields (but not DSN)
+	Params map[string]string
+}
+
+// Apply the Params over the other fields: to the fields for their key, and the rest as driver options.
+func (c ConnectionConfig) withParams() ConnectionConfig {
+	if len(c.Params) == 0 {
+		return c
+	}
+
+	// The port and instance belong to the server they replace
+	if server, ok := c.Params[msdsn.Server]; ok {
+		c.Server, c.Port, c.Instance = server, "", ""
+	}
+	options := maps.Clone(c.DriverOptions)
+	if options == nil {
+		options = map[string]string{}
+	}
+	for key, value := range c.Params {
+		switch key {
+		case msdsn.Server:
+		case msdsn.Port:
+			c.Port = value
+		case msdsn.Database:
+			c.Database, c.InitialCatalog = value, ""
+		case msdsn.UserID:
+			c.User = value
+		case msdsn.Password:
+			c.Password = value
+		case msdsn.AppName:
+			c.AppName = value
+		default:
+			options[key] = value
+		}
+	}
+	c.DriverOptions = options
+	return c
+}
+
+// Encryption modes of the driver. ENCRYPT_DISABLE turns TLS off entirely, for a local SQL Server container without TLS.
+const (
+	ENCRYPT_TRUE    string = "true"
+	ENCRYPT_FALSE   string = "false"
+	ENCRYPT_STRICT  string = "strict"
+	ENCRYPT_DISABLE string = "disable"
+)
+
+// Network protocols of the driver. Named pipes and shared memory are only available on Windows, and not for Azure SQL.
+const (
+	PROTOCOL_TCP           string = "tcp"
+	PROTOCOL_NAMED_PIPE    string = "np"
+	PROTOCOL_SHARED_MEMORY string = "lpc"
+)
+
+// Hostname suffix of the serverless SQL pool of a Synapse workspace.
+const synapseServerlessSuffix = "-ondemand.sql.azuresynapse.net"
+
+// Check that the connection details are complete and in range. A DSN is validated by the driver instead.
+func (c ConnectionConfig) Validate() error {
+	if c.DSN != "" {
+		return nil
+	}
+
+	if strings.TrimSpace(c.Server) == "" {
+		return errors.New("no connection string provided via --dsn flag, or server via --server flag or environment variables")
+	}
+
+	if c.SynapseServerless && !c.TokenAuth {
+		return errors.New("a Synapse serverless SQL pool needs a Microsoft Entra login, use --auth=azure-default (or token-file) instead of SQL auth")
+	}
+
+	switch c.Encrypt {
+	case "", ENCRYPT_TRUE, ENCRYPT_STRICT:
+	case ENCRYPT_FALSE, ENCRYPT_DISABLE:
+		if c.SynapseServerless {
+			return fmt.Errorf("a Synapse serverless SQL pool needs an encrypted connection, so --encrypt=%s is not supported", c.Encrypt)
+		}
+	default:
+		return fmt.Errorf("unknown encryption mode '%s', use %s, %s, %s or %s", c.Encrypt, ENCRYPT_TRUE, ENCRYPT_FALSE, ENCRYPT_STRICT, ENCRYPT_DISABLE)
+	}
+
+	if c.User == "" && !c.TokenAuth {
+		return errors.New("no user provided via --user flag or environment variables")
+	}
+
+	switch c.Protocol {
+	case "", PROTOCOL_TCP:
+	case PROTOCOL_NAMED_PIPE, PROTOCOL_SHARED_MEMORY:
+		if runtime.GOOS != "windows" {
+			return fmt.Errorf("protocol '%s' is only supported on Windows, use %s", c.Protocol, PROTOCOL_TCP)
+		}
+	default:
+		return fmt.Errorf("unknown protocol '%s', use %s, %s or %s", c.Protocol, PROTOCOL_TCP, PROTOCOL_NAMED_PIPE, PROTOCOL_SHARED_MEMORY)
+	}
+
+	if c.Port != "" {
+		if n, err := strconv.Atoi(c.Port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port '%s', use a number from 1 to 65535", c.Port)
+		}
+	}
+
+	if c.DialTimeout < 0 {
+		return fmt.Errorf("invalid dial timeout %v, use a positive duration (or 0 for the default)", c.DialTimeout)
+	}
+
+	return nil
+}
+
+// Parse a TLS version, like "1.2", into its tls.VersionTLS constant.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version '%s', use 1.0, 1.1, 1.2 or 1.3", version)
+	}
+}
+
+// Parse comma-separated key=value driver options, like "PacketSize=8192,Workstation ID=etl".
+func ParseDriverOptions(options string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, option := range strings.Split(options, ",") {
+		if strings.TrimSpace(option) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(option, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid driver option '%s', use key=value", option)
+		}
+		parsed[key] = strings.TrimSpace(value)
+	}
+	return parsed, nil
+}
+
 // Build connection string for Azure SQL Database from environment variables.
-func BuildDSN(
-	server string,
-	port string,
-	instance string,
-	database string,
-	username string,
-	password string,
-	dsn string,
-) string {
-	if dsn != "" {
-		return dsn
+func BuildDSN(config ConnectionConfig) (string, error) {
+	config = config.withParams()
+	if err := config.Validate(); err != nil {
+		return "", err
+	}
+
+	if config.DSN != "" {
+		return config.DSN, nil
+	}
+
+	server, port, instance, err := NormalizeServer(config.Server, config.Port, config.Instance)
+	if err != nil {
+		return "", err
+	}
+
+	if config.SynapseServerless && !strings.Contains(server, ".") && net.ParseIP(server) == nil {
+		server += synapseServerlessSuffix
+	}
+
+	appName := config.AppName
+	if appName == "" {
+		appName = DefaultAppName
 	}
 
 	q := url.Values{}
-	q.Add("AppName", "ghcr.io/redmer/azure-wakeup-db")
-	q.Add("DisableRetry", fmt.Sprintf("%t", false))
+	q.Add("AppName", appName)
+	q.Add("DisableRetry", fmt.Sprintf("%t", config.DisableDriverRetry))
+
+	timeout := config.DialTimeout
+	if timeout <= 0 {
+		timeout = time.Duration(5) * time.Minute
+	}
+	// In whole seconds, rounded up as 0 would disable the timeout
+	q.Add("DialTimeout", strconv.FormatFloat(math.Ceil(timeout.Seconds()), 'f', 0, 64))
+
+	if config.InitialCatalog != "" {
+		q.Add("database", config.InitialCatalog)
+	} else if config.Database != "" {
+		q.Add("database", config.Database)
+	}
 
-	timeout := time.Duration(5) * time.Minute // 5 min timeout
-	q.Add("DialTimeout", strconv.FormatFloat(float64(timeout/time.Second), 'f', 0, 64))
+	if config.Encrypt != "" {
+		q.Add("encrypt", config.Encrypt)
+	} else if config.SynapseServerless {
+		q.Add("encrypt", ENCRYPT_TRUE)
+	}
+
+	// Explicit, as the driver otherwise also tries the other protocols (on Windows)
+	protocol := config.Protocol
+	if protocol == "" {
+		protocol = PROTOCOL_TCP
+	}
+	q.Add("protocol", protocol)
+
+	if config.ReadOnly {
+		q.Add("ApplicationIntent", "ReadOnly")
+	}
+
+	if config.MultiSubnetFailover != nil {
+		q.Add("MultiSubnetFailover", fmt.Sprintf("%t", *config.MultiSubnetFailover))
+	}
+
+	if config.TCPKeepAlive != nil {
+		// In whole seconds, rounded up as 0 would disable the probes
+		q.Add("keepAlive", strconv.FormatFloat(math.Ceil(config.TCPKeepAlive.Seconds()), 'f', 0, 64))
+	}
+
+	// Parameter names are case-insensitive to the driver, so an option replaces a default in any case
+	for key, value := range config.DriverOptions {
+		for existing := range q {
+			if strings.EqualFold(existing, key) {
+				q.Del(existing)
+			}
+		}
+		q.Set(key, value)
+	}
 
-	if database != "" {
-		q.Add("database", database)
+	// Without a port, the driver asks the SQL Browser for the port of a named instance
+	if port == "" && instance == "" {
+		port = "1433"
+	}
+	host := net.JoinHostPort(server, port) // brackets IPv6 literals
+	if port == "" {
+		host = strings.TrimSuffix(host, ":")
 	}
 
 	res := url.URL{
 		Scheme: "sqlserver",
-		Host:   fmt.Sprintf("%s:%s", server, port),
-		User:   url.UserPassword(username, password),
+		Host:   host,
+	}
+	// Not with an access token, which is passed to the connector instead
+	if config.User != "" || config.Password != "" {
+		res.User = url.UserPassword(config.User, config.Password)
 	}
 
 	if instance != "" {
@@ -63,7 +404,24 @@ func BuildDSN(
 		res.RawQuery = q.Encode()
 	}
 
-	return res.String()
+	return res.String(), nil
+}
+
+// Whether an option was explicitly set, either by command line flag or environment variable.
+func IsSet(fs *flag.FlagSet, name, key string) bool {
+	value, exists := lookupEnv(key)
+	return IsFlagSet(fs, name) || (exists && value != "")
+}
+
+// Whether an option was set on the command line, not just by its environment variable.
+func IsFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 // If error provided is an Azure SQL throttling error (40613), also caused by paused instances.
@@ -75,136 +433,967 @@ func isThrottlingError(err error) bool {
 	return strings.Contains(err.Error(), "is not currently available.  Please retry the connection later")
 }
 
-// Add 10% timing jitter to a time.Duration
-func addJitter(delay time.Duration) time.Duration {
-	jitter := time.Duration(rand.Float64() * float64(delay) * 0.1)
+// A wait hint in an error message, like "Please retry the connection after 30 seconds".
+var retryHintPattern = regexp.MustCompile(`(?i)retry(?: the \w+)? (?:after|in) (\d+) seconds?`)
+
+// The delay a throttling error asks to wait before retrying, if it includes one.
+func retryHint(err error) (time.Duration, bool) {
+	if !isThrottlingError(err) {
+		return 0, false
+	}
+
+	m := retryHintPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(m[1])
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// If error provided is a transient network failure, common while a serverless server spins up.
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// SQL error numbers of login failures: wrong user or password, disabled or locked account, expired password.
+var authenticationErrorNumbers = []int32{18456, 18452, 18470, 18486, 18487, 18488}
+
+// The SQL error number of an error, if it is or wraps a mssql.Error.
+func sqlErrorNumber(err error) (int32, bool) {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Number, true
+	}
+	return 0, false
+}
+
+// The structured SQL error of an error, if it is or wraps a mssql.Error.
+func sqlErrorOf(err error) *SQLError {
+	var sqlErr mssql.Error
+	if errors.As(err, &sqlErr) {
+		return &SQLError{Number: sqlErr.Number, Line: sqlErr.LineNo, Message: sqlErr.Message}
+	}
+	return nil
+}
+
+// If error provided is a login failure.
+func isAuthenticationError(err error) bool {
+	number, ok := sqlErrorNumber(err)
+	return ok && slices.Contains(authenticationErrorNumbers, number)
+}
+
+// SQL error numbers of connections denied by the server firewall: the client IP is not allowed.
+var firewallErrorNumbers = []int32{40615, 40914}
+
+// If error provided is a connection denied by the server firewall.
+func isFirewallError(err error) bool {
+	number, ok := sqlErrorNumber(err)
+	return ok && slices.Contains(firewallErrorNumbers, number)
+}
+
+// Returned when the connection works, but the ping query fails. Retried, as the database may not be ready yet.
+var ErrPingQuery = errors.New("ping query failed")
+
+// Returned when a query after the ping fails with --verify-stable or --readiness-queries. Retried, like a failed ping query.
+var ErrUnstable = errors.New("database not stable yet")
+
+// SQL error numbers of transient Azure SQL conditions: database unavailable or resuming (40613), service busy (40501),
+// error processing the request (40197), resource limits (10928, 10929) and elastic pool limits (49918, 49919, 49920).
+// Can be changed before connecting, to retry more or fewer errors.
+var RetryableErrorNumbers = []int32{40613, 40501, 40197, 10928, 10929, 49918, 49919, 49920}
+
+// Returned when an attempt fails with one of the error numbers of RetryConfig.AbortOn.
+var ErrAborted = errors.New("aborted on error")
+
+// Parse comma-separated SQL error numbers, like "40613,18456".
+func ParseErrorNumbers(numbers string) ([]int32, error) {
+	var parsed []int32
+	for _, number := range strings.Split(numbers, ",") {
+		if number = strings.TrimSpace(number); number == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(number, 10, 32)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid error number '%s', use a positive number", number)
+		}
+		parsed = append(parsed, int32(n))
+	}
+	return parsed, nil
+}
+
+// If error provided is worth another attempt: throttling, a transient SQL error or network failure, or a failed ping query.
+// Other errors, like authentication failures, firewall denials or bad configuration, are not.
+// Usable in other retry loops, as it only depends on the error.
+func IsRetryable(err error) bool {
+	if err == nil || isAuthenticationError(err) || isFirewallError(err) {
+		return false
+	}
+
+	if number, ok := sqlErrorNumber(err); ok && slices.Contains(RetryableErrorNumbers, number) {
+		return true
+	}
+	return isThrottlingError(err) || isTransientNetworkError(err) || errors.Is(err, ErrPingQuery) || errors.Is(err, ErrUnstable)
+}
+
+// Add up to percent% timing jitter to a time.Duration.
+// Takes values from r if given, so tests can seed it, or from the global source.
+func addJitter(delay time.Duration, percent int, r *rand.Rand) time.Duration {
+	f := rand.Float64
+	if r != nil {
+		f = r.Float64
+	}
+	jitter := time.Duration(f() * float64(delay) * float64(percent) / 100)
 	return delay + jitter
 }
 
+// A random delay in [0, limit), to stagger runs that start at the same time. Drawn from r, or the global source if nil.
+func startupJitter(limit time.Duration, r *rand.Rand) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	if r != nil {
+		return time.Duration(r.Int64N(int64(limit)))
+	}
+	return time.Duration(rand.Int64N(int64(limit)))
+}
+
+// Retry policy for ThrottledRetry and Do.
+type RetryConfig struct {
+	MaxRetries int
+	RetryDelay time.Duration
+	NoJitter   bool // Pause exactly RetryDelay, for reproducible timing
+
+	// Optional: maximum jitter added to the delay, as a percentage of it. Defaults to 10.
+	JitterPercent int
+
+	// Optional: no new attempt is made if it would start later than this after the first one
+	MaxElapsed time.Duration
+
+	// Optional: upper bound of a wait hint in a throttling error, used instead of RetryDelay. Defaults to 4 × RetryDelay.
+	// Also bounds the growing delay of a Multiplier.
+	MaxDelay time.Duration
+
+	// Optional: factor the delay grows by after each retry, for exponential backoff.
+	// Any value up to 1 (including 0, a negative value or NaN) means a constant delay, which is the default.
+	Multiplier float64
+
+	// Optional: whether an error is worth another attempt. Defaults to IsRetryable.
+	Retryable func(err error) bool
+
+	// Optional: called before each pause between attempts, after the retry has been logged.
+	// Receives the upcoming attempt number (starting at 2), the delay and the error of the previous attempt.
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	// Optional: called right before each attempt, with the attempt number (starting at 1).
+	OnAttempt func(attempt int)
+
+	// Optional: SQL error numbers that end the retries right away with ErrAborted, even if they are retryable.
+	AbortOn []int32
+
+	// Optional: limits how fast attempts start. Shared by the configs of a batch, to cap the logins to a server.
+	Limiter *rate.Limiter
+
+	// Optional: where attempts are logged. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// The configured bound of a wait hint, or the default.
+func (c RetryConfig) maxDelay() time.Duration {
+	if c.MaxDelay > 0 {
+		return c.MaxDelay
+	}
+	return 4 * c.RetryDelay
+}
+
+// The delay before an attempt (starting at 1 for the first retry), without jitter.
+func (c RetryConfig) delay(attempt int) time.Duration {
+	if !(c.Multiplier > 1) { // also NaN
+		return c.RetryDelay
+	}
+	// Compared as a float, as a huge delay does not fit a time.Duration
+	delay := float64(c.RetryDelay) * math.Pow(c.Multiplier, float64(attempt-1))
+	if delay >= float64(c.maxDelay()) {
+		return c.maxDelay()
+	}
+	return time.Duration(delay)
+}
+
+// The configured predicate of retryable errors, or IsRetryable.
+func (c RetryConfig) retryable() func(err error) bool {
+	if c.Retryable != nil {
+		return c.Retryable
+	}
+	return IsRetryable
+}
+
+// The configured jitter percentage, or the default one.
+func (c RetryConfig) jitterPercent() int {
+	if c.JitterPercent > 0 {
+		return c.JitterPercent
+	}
+	return 10
+}
+
+// The configured logger, or the default one.
+func (c RetryConfig) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// The pause before each attempt (zero for the first), without jitter.
+func (c RetryConfig) Schedule() []time.Duration {
+	schedule := make([]time.Duration, c.MaxRetries)
+	for attempt := 1; attempt < c.MaxRetries; attempt++ {
+		schedule[attempt] = c.delay(attempt)
+	}
+	return schedule
+}
+
+// The time left before the deadline when an attempt starts after delay, like ", remaining budget: 3m12s".
+// If the attempt after it could not start in time either, this is the final attempt, which is noted too.
+func budgetNote(deadline time.Time, hasDeadline bool, delay, retryDelay time.Duration) string {
+	if !hasDeadline {
+		return ""
+	}
+	remaining := max(time.Until(deadline)-delay, 0)
+	note := fmt.Sprintf(", remaining budget: %v", remaining.Round(time.Second))
+	if remaining < retryDelay {
+		note += " (final attempt)"
+	}
+	return note
+}
+
+// Returned when no new attempt is made, because it would start after the elapsed budget or the deadline.
+var ErrOutOfTime = errors.New("out of time")
+
 // With pauses, Retry to connect until the maximum number of retries is reached.
-func ThrottledRetry[T any](ctx context.Context, closure func() (T, error), maxRetries int, retryDelay time.Duration) (T, error) {
+func ThrottledRetry[T any](ctx context.Context, closure func() (T, error), config RetryConfig) (T, error) {
 	var zeroValue T
 	var lastErr error
+	logger := config.logger()
+
+	// New attempts must start before the tighter of the elapsed budget and the context deadline
+	deadline, hasDeadline := ctx.Deadline()
+	if budget := time.Now().Add(config.MaxElapsed); config.MaxElapsed > 0 && (!hasDeadline || budget.Before(deadline)) {
+		deadline, hasDeadline = budget, true
+	}
 
-	for attempt := range maxRetries {
+	for attempt := range config.MaxRetries {
 		select {
 		case <-ctx.Done():
 			return zeroValue, ctx.Err()
 		default:
 			if attempt > 0 {
-				log.Printf("attempt %d/%d after %v delay", attempt+1, maxRetries, retryDelay)
-				time.Sleep(addJitter(retryDelay))
+				planned := config.delay(attempt)
+				delay := planned
+				if hint, ok := retryHint(lastErr); ok {
+					// Honored exactly, as the server knows better than the jitter
+					delay = min(hint, config.maxDelay())
+					planned = delay
+					logger.Info(fmt.Sprintf("Honoring the wait hint of %v from the server, waiting %v.", hint, delay))
+				} else if !config.NoJitter {
+					delay = addJitter(delay, config.jitterPercent(), nil)
+				}
+				if hasDeadline && time.Now().Add(delay).After(deadline) {
+					return zeroValue, fmt.Errorf("%w after %d attempts: %w", ErrOutOfTime, attempt, lastErr)
+				}
+
+				logger.Info(fmt.Sprintf("attempt %d/%d after %v delay%s", attempt+1, config.MaxRetries, planned, budgetNote(deadline, hasDeadline, delay, config.delay(attempt+1))))
+				if config.OnRetry != nil {
+					config.OnRetry(attempt+1, planned, lastErr)
+				}
+				time.Sleep(delay)
 			} else {
-				log.Printf("attempt 1/%d", maxRetries)
+				logger.Info(fmt.Sprintf("attempt 1/%d%s", config.MaxRetries, budgetNote(deadline, hasDeadline, 0, config.delay(1))))
 			}
 
+			if config.Limiter != nil {
+				if err := config.Limiter.Wait(ctx); err != nil {
+					if lastErr == nil {
+						return zeroValue, fmt.Errorf("%w waiting for --max-login-rate: %v", ErrOutOfTime, err)
+					}
+					return zeroValue, fmt.Errorf("%w after %d attempts: %w", ErrOutOfTime, attempt, lastErr)
+				}
+			}
+			if config.OnAttempt != nil {
+				config.OnAttempt(attempt + 1)
+			}
 			result, err := closure()
 			if err == nil { // success
 				return result, nil
 			}
 
 			lastErr = err
-			if !isThrottlingError(err) { // not throttling error
+			if number, ok := sqlErrorNumber(err); ok && slices.Contains(config.AbortOn, number) {
+				return zeroValue, fmt.Errorf("%w %d: %w", ErrAborted, number, err)
+			}
+			if !config.retryable()(err) { // not throttling or transient error
 				return zeroValue, err
 			}
 		}
 	}
 
-	return zeroValue, fmt.Errorf("failed after %d attempts: %v", maxRetries, lastErr)
+	return zeroValue, fmt.Errorf("failed after %d attempts: %w", config.MaxRetries, lastErr)
+}
+
+// Run fn with the retry policy, like ThrottledRetry, for an operation without a result.
+func (c RetryConfig) Do(ctx context.Context, fn func() error) error {
+	_, err := ThrottledRetry(ctx, func() (struct{}, error) {
+		return struct{}{}, fn()
+	}, c)
+	return err
 }
 
-// Return a working sql.DB connection based on a connection string
-func ConnectAndPing(connString string) (*sql.DB, error) {
-	db, err := sql.Open("sqlserver", connString)
+// Return a working sql.DB connection based on the connection string in the config.
+// Network connections are made with config.Dialer if set: a NewProxyDialer (--proxy) tunnels them through the proxy, which it
+// reaches from the local address of --local-addr if that is also set, and a NewLocalDialer (--local-addr) alone binds them to
+// the local address. Without one, the driver dials the server directly.
+// The attempt is aborted when ctx is done, or after the per-attempt timeout.
+func ConnectAndPing(ctx context.Context, config Config) (*sql.DB, error) {
+	return connectAndPing(ctx, config, nil)
+}
+
+// ConnectAndPing, which also measures where the time of the attempt went into timing, if it is not nil.
+func connectAndPing(ctx context.Context, config Config, timing *Timing) (*sql.DB, error) {
+	params, err := msdsn.Parse(config.ConnectionString)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %v", err)
 	}
 
+	// Also applies to a DSN, unlike a tlsmin parameter in the connection string
+	if config.TLSMinVersion != 0 && params.TLSConfig != nil {
+		params.TLSConfig.MinVersion = config.TLSMinVersion
+	}
+
+	// The driver logger is global, but all connections of a run are traced alike
+	if config.Trace && config.Logger != nil {
+		params.LogFlags |= traceLogFlags
+		mssql.SetContextLogger(driverLogger{logger: config.Logger, secrets: []string{params.Password}})
+	}
+
+	var connector *mssql.Connector
+	if config.LoginCredential != nil {
+		connector, err = mssql.NewSecurityTokenConnector(params, func(ctx context.Context) (string, error) {
+			token, err := config.LoginCredential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{sqlTokenScope}})
+			return token.Token, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error opening database: %v", err)
+		}
+	} else {
+		connector = mssql.NewConnectorConfig(params)
+	}
+	dialer := config.Dialer
+	var td *timingDialer
+	if timing != nil {
+		if dialer == nil {
+			// Like the default dialer of the driver
+			dialer = &net.Dialer{KeepAlive: params.KeepAlive}
+		}
+		td, dialer = newTimingDialer(dialer)
+	}
+	if dialer != nil {
+		connector.Dialer = dialer
+	}
+	db := sql.OpenDB(connector)
+
 	// Set connection pool settings
 	db.SetMaxOpenConns(5)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(6 * time.Minute)
 
-	// Test connection with context, 5 minute timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// Test connection with context, bounded by the per-attempt timeout
+	ctx, cancel := context.WithTimeout(ctx, config.attemptTimeout())
 	defer cancel()
 
-	err = db.PingContext(ctx)
+	start := time.Now()
+	if td != nil {
+		err = timedPing(ctx, db, td, start, timing)
+	} else {
+		err = db.PingContext(ctx)
+	}
 	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("error connecting to database: %v", err)
+		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
+	pinged := time.Now()
 
+	// Only checks that the database is accessible: it does not resume a paused serverless database
+	if config.UseDatabase != "" {
+		_, err = db.ExecContext(ctx, "USE "+mssql.TSQLQuoter{}.ID(config.UseDatabase))
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error switching to database '%s': %w", config.UseDatabase, err)
+		}
+	}
+
+	// The ping is the first readiness query
+	for i := 1; i < config.ReadinessQueries; i++ {
+		if _, err := db.ExecContext(ctx, "SELECT 1"); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%w: readiness query %d/%d: %w", ErrUnstable, i+1, config.ReadinessQueries, err)
+		}
+	}
+
+	if config.PingQuery != "" {
+		if err := runPingQuery(ctx, db, config); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if config.VerifyStable {
+		if err := verifyStable(ctx, db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if timing != nil {
+		timing.QueriesMs = time.Since(pinged).Milliseconds()
+	}
 	return db, nil
 }
 
-const (
-	WAKEUP_USER     string = "WAKEUP_USER"
-	WAKEUP_PASSWORD string = "WAKEUP_PASSWORD"
-	WAKEUP_SERVER   string = "WAKEUP_SERVER"
-	WAKEUP_INSTANCE string = "WAKEUP_INSTANCE"
-	WAKEUP_DATABASE string = "WAKEUP_DATABASE"
-	WAKEUP_PORT     string = "WAKEUP_PORT"
-	WAKEUP_DSN      string = "WAKEUP_DSN"
-)
+// Ping on a connection of its own, to tell the time of opening it from the time of the ping.
+func timedPing(ctx context.Context, db *sql.DB, td *timingDialer, start time.Time, timing *Timing) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() // Back to the pool, for the queries after the ping
 
-// Ensure a connection with an Azure DB that may be auto-paused.
-// Wait and try for 5 minutes to wake it up.
-func main() {
-	server := flag.String("server", os.Getenv(WAKEUP_SERVER), "Database server")
-	port := flag.String("port", GetEnv(WAKEUP_PORT, "1433"), "Database port")
-	instance := flag.String("instance", os.Getenv(WAKEUP_INSTANCE), "SQL Server instance name")
-	database := flag.String("database", os.Getenv(WAKEUP_DATABASE), "Database name")
-	user := flag.String("user", os.Getenv(WAKEUP_USER), "Database user")
-	password := flag.String("password", os.Getenv(WAKEUP_PASSWORD), "Database password")
-	dsn := flag.String("dsn", os.Getenv(WAKEUP_DSN), "Database connection string")
-	help := flag.Bool("help", false, "Show this help message")
-	verbose := flag.Bool("verbose", false, "Verbose output")
+	connected := time.Now()
+	err = conn.PingContext(ctx)
+	*timing = td.timing(start, connected)
+	timing.PingMs = time.Since(connected).Milliseconds()
+	return err
+}
+
+// Pause between the retries of a failed ping query on the open connection. Shorter in tests.
+var verifyRetryInterval = time.Second
+
+// Run the ping query on the open connection. A failure is retried VerifyRetries times, verifyRetryInterval apart,
+// before the whole attempt fails and a new connection is made.
+func runPingQuery(ctx context.Context, db *sql.DB, config Config) error {
+	var err error
+	for try := 0; ; try++ {
+		if config.Logger != nil && config.Logger.Enabled(ctx, slog.LevelDebug) {
+			err = ExecWithMessages(ctx, db, config.PingQuery, config.Logger)
+		} else {
+			_, err = db.ExecContext(ctx, config.PingQuery)
+		}
+		if err == nil {
+			return nil
+		}
+		if try >= config.VerifyRetries {
+			return fmt.Errorf("%w: %w", ErrPingQuery, err)
+		}
+		if config.Logger != nil {
+			config.Logger.Debug(fmt.Sprintf("ping query failed, retrying on the open connection (%d/%d): %v", try+1, config.VerifyRetries, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrPingQuery, err)
+		case <-time.After(verifyRetryInterval):
+		}
+	}
+}
+
+// Run a few more queries, a second apart, as the compute of a just resumed serverless database may still be scaling.
+func verifyStable(ctx context.Context, db *sql.DB) error {
+	for range 2 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrUnstable, ctx.Err())
+		case <-time.After(time.Second):
+		}
+
+		if _, err := db.ExecContext(ctx, "SELECT 1"); err != nil {
+			return fmt.Errorf("%w: %w", ErrUnstable, err)
+		}
+	}
+	return nil
+}
+
+// Configuration for waking up a database.
+type Config struct {
+	ConnectionString string
+	Fallbacks        []string     // Optional: connection strings of other servers, tried in order if ConnectionString fails
+	Dialer           mssql.Dialer // Optional: make all network connections through this dialer
+	TLSMinVersion    uint16       // Optional: minimum TLS version, like tls.VersionTLS12, instead of the driver default
+	Retry            RetryConfig
+
+	Timeout        time.Duration // Bounds all attempts on a server together. Defaults to 5 minutes.
+	AttemptTimeout time.Duration // Bounds a single connection attempt. Defaults to Timeout.
+	SharedTimeout  bool          // Timeout bounds all servers together, so the fallbacks get the time the primary left
+
+	// Optional: after logging in to the initial catalog, switch to this database with USE.
+	// A serverless database is only resumed by a login that targets it, not by switching to it.
+	UseDatabase string
+
+	// Optional: after the ping, run this query as part of the attempt. If it fails, the attempt is retried.
+	PingQuery string
+	// Consecutive queries that must succeed, counting the ping. Up to 1 means just the ping.
+	ReadinessQueries int
+	// Retries of a failed PingQuery on the open connection, before the attempt fails
+	VerifyRetries int
+	// After the wake-up, run SELECT 1 on this many concurrent connections for WarmDuration, to scale out compute
+	WarmConnections int
+	WarmDuration    time.Duration
+	// Log the TDS-level diagnostics of the driver through Logger, at debug level
+	Trace bool
+	// Measure where the time of the successful attempt went, into the result
+	Timing bool
+
+	// After the ping (and PingQuery), require two more queries a second apart to succeed, as part of the attempt
+	VerifyStable bool
+
+	// After connecting, poll until the database status is ONLINE
+	WaitForOnline bool
 
-	flag.Parse()
+	// Optional: after connecting (and WaitForOnline), poll until the database has at least this many vCores
+	MinVCores float64
 
-	if *help {
-		why := `Connect to awaken a paused Azure DB.
+	// After connecting, read the server and database name into the result
+	VerifyIdentity bool
 
-  Provide connection details to connect. All environment variable name start with WAKEUP_<option_name>.
-  Command line arguments have higher priority. The DSN option always overrides any and all other values.`
+	// Optional: before connecting, ask the Azure management API to resume this database with the credential
+	Resume     *AzureDatabase
+	Credential azcore.TokenCredential
 
-		fmt.Println(why)
-		flag.Usage()
+	// Optional: log in with an access token from this credential, instead of the user and password
+	LoginCredential azcore.TokenCredential
 
-		os.Exit(0)
+	// Optional: where progress is logged, also used for retries if Retry.Logger is unset. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// The configured overall timeout, or the default.
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 5 * time.Minute
+}
+
+// The configured per-attempt timeout, or the overall timeout.
+func (c Config) attemptTimeout() time.Duration {
+	if c.AttemptTimeout > 0 {
+		return c.AttemptTimeout
+	}
+	return c.timeout()
+}
+
+// Validate the connection details of a config, without connecting.
+func Check(config Config) CheckResult {
+	result := CheckResult{}
+	c, err := msdsn.Parse(config.ConnectionString)
+	if err != nil {
+		msg := fmt.Sprintf("invalid connection string: %v", err)
+		result.Error = &msg
+		return result
+	}
+
+	result.Server = c.Host
+	result.Database = c.Database
+	if config.UseDatabase != "" {
+		result.Database = config.UseDatabase
+	}
+	return result
+}
+
+// A total time budget shared by a batch of databases that are woken up one after the other.
+// Each database gets an equal share of the time that is left, so time left over by a quick database goes to the rest,
+// and a stuck database cannot starve the ones after it.
+type SharedBudget struct {
+	deadline time.Time
+	left     int
+}
+
+func NewSharedBudget(total time.Duration, databases int) *SharedBudget {
+	return &SharedBudget{deadline: time.Now().Add(total), left: databases}
+}
+
+// The time budget of the next database.
+func (b *SharedBudget) Next() time.Duration {
+	share := time.Until(b.deadline)
+	if b.left > 1 {
+		share /= time.Duration(b.left)
+		b.left--
+	}
+	// A zero timeout means the default, so an exhausted budget is a tiny one
+	return max(share, time.Nanosecond)
+}
+
+// If error provided means the time ran out, before or during an attempt.
+func isOutOfTime(err error) bool {
+	return errors.Is(err, ErrOutOfTime) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// Wake up the database behind a connection string, and report on how that went.
+func Wakeup(config Config) Result {
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if config.Retry.Logger == nil {
+		config.Retry.Logger = logger
 	}
 
-	connectionString := *dsn
-	// If no DSN provided, try to build from environment variables and passed arguments
-	if connectionString == "" {
-		connectionString = BuildDSN(*server, *port, *instance, *database, *user, *password, *dsn)
+	result := Result{}
+	user := ""
+	if c, err := msdsn.Parse(config.ConnectionString); err == nil {
+		result.Server = c.Host
+		result.Database = c.Database
+		user = c.User
+	}
+	if config.UseDatabase != "" {
+		result.Database = config.UseDatabase
 	}
 
-	if *verbose {
-		log.Printf("Connecting with '%v'.\n", connectionString)
+	start := time.Now()
+	deadline := start.Add(config.timeout())
+	var err error
+	// Try the fallback servers in order, unless the login itself is rejected or an --abort-on error occurred
+	for i, connectionString := range append([]string{config.ConnectionString}, config.Fallbacks...) {
+		if i > 0 && config.SharedTimeout && !time.Now().Before(deadline) {
+			logger.Warn(fmt.Sprintf("could not wake up on '%s', and no time is left for the fallback servers: %v", result.Server, err))
+			break
+		}
+		if i > 0 {
+			logger.Warn(fmt.Sprintf("could not wake up on '%s', trying fallback server: %v", result.Server, err))
+			if c, err := msdsn.Parse(connectionString); err == nil {
+				result.Server = c.Host
+			}
+		}
+		config.ConnectionString = connectionString
+
+		serverDeadline := time.Now().Add(config.timeout())
+		if config.SharedTimeout {
+			serverDeadline = deadline
+		}
+		ctx, cancel := context.WithDeadline(context.Background(), serverDeadline)
+		if i == 0 && config.Resume != nil {
+			// The connection attempts below may still resume the database, so this is not fatal
+			if err := ResumeViaAPI(ctx, config.Credential, *config.Resume); err != nil {
+				logger.Warn(fmt.Sprintf("could not resume via Azure management API: %v", err))
+			} else {
+				logger.Info("Requested resume via Azure management API.")
+			}
+		}
+		serverStart, attempts := time.Now(), result.Attempts
+		err = wakeupServer(ctx, config, &result, logger)
+		cancel()
+
+		server := ServerResult{
+			Server:    result.Server,
+			Success:   err == nil,
+			Attempts:  result.Attempts - attempts,
+			ElapsedMs: time.Since(serverStart).Milliseconds(),
+		}
+		if err != nil {
+			msg := err.Error()
+			server.Error = &msg
+			server.Throttled = isThrottlingError(err)
+			server.TimedOut = isOutOfTime(err)
+		}
+		result.Servers = append(result.Servers, server)
+		if err == nil {
+			result.connectionString = connectionString
+		}
+
+		if err == nil || isAuthenticationError(err) || errors.Is(err, ErrAborted) {
+			break
+		}
 	}
+	result.ElapsedMs = time.Since(start).Milliseconds()
 
-	if connectionString == "" || strings.HasPrefix(connectionString, "sqlserver://:@:1433?") {
-		log.Fatal("error: no connection string provided via --dsn flag or environment variables")
+	if isAuthenticationError(err) && user != "" {
+		err = fmt.Errorf("authentication failed for user '%s': %w", user, err)
+	} else if isAuthenticationError(err) {
+		err = fmt.Errorf("authentication failed: %w", err)
+	} else if isFirewallError(err) {
+		err = fmt.Errorf("client IP not allowed by the firewall of server '%s', add a firewall rule for it: %w", result.Server, err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	if err != nil {
+		msg := err.Error()
+		result.Error = &msg
+		result.SQLError = sqlErrorOf(err)
+		result.Throttled = isThrottlingError(err)
+		result.TimedOut = isOutOfTime(err)
+		result.Aborted = errors.Is(err, ErrAborted)
+		return result
+	}
+
+	result.Success = true
+	// Only the connection counts: the steps after it, like --warm-connections, take long on an awake database too
+	result.AlreadyAwake = result.Attempts == 1 && result.connectElapsed <= alreadyAwakeThreshold
+	if result.AlreadyAwake {
+		logger.Info(fmt.Sprintf("Database '%s' was already awake.", result.Database))
+	} else {
+		logger.Info(fmt.Sprintf("Database '%s' resumed after %v and %d attempts.", result.Database, (time.Duration(result.ElapsedMs) * time.Millisecond).Round(time.Second), result.Attempts))
+	}
+	return result
+}
+
+// Log the addresses the server hostname resolves to, to tell a private endpoint from the public one.
+// A proxy resolves the hostname itself, so then there is nothing to log.
+func logResolvedServer(ctx context.Context, config Config, logger *slog.Logger) {
+	c, err := msdsn.Parse(config.ConnectionString)
+	if err != nil || net.ParseIP(c.Host) != nil {
+		return
+	}
+	if _, ok := config.Dialer.(mssql.HostDialer); ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, c.Host)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("could not resolve server '%s': %v", c.Host, err))
+		return
+	}
+	for i, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.IsPrivate() {
+			addrs[i] += " (private)"
+		}
+	}
+	logger.Debug(fmt.Sprintf("Server '%s' resolves to %s.", c.Host, strings.Join(addrs, ", ")))
+}
+
+// How wakeupServer makes a connection attempt. A variable, so tests can connect without a server.
+var connectAttempt = connectAndPing
+
+// Connect to the server of config.ConnectionString with retries, counting the attempts in result.
+// Then wait until it is online and read its identity, if configured.
+func wakeupServer(ctx context.Context, config Config, result *Result, logger *slog.Logger) error {
+	logger.Debug(fmt.Sprintf("Connecting with '%v'.", RedactConnectionString(config.ConnectionString)))
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		logResolvedServer(ctx, config, logger)
+	}
 
 	// Actually make the connection with the database
+	connectStart := time.Now()
 	conn, err := ThrottledRetry(
 		ctx,
 		func() (*sql.DB, error) {
-			return ConnectAndPing(connectionString)
+			result.Attempts++
+			if !config.Timing {
+				return connectAttempt(ctx, config, nil)
+			}
+			var timing Timing
+			db, err := connectAttempt(ctx, config, &timing)
+			if err == nil {
+				result.Timing = &timing
+			}
+			return db, err
 		},
-		15,
-		time.Duration(25)*time.Second,
+		config.Retry,
 	)
+	result.connectElapsed = time.Since(connectStart)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	defer conn.Close()
 
-	log.Println("Connection successful: database is awake.")
+	if result.Timing != nil {
+		logger.Info(fmt.Sprintf("Timing of the successful attempt: %v.", result.Timing))
+	}
+
+	if config.WaitForOnline {
+		if err := WaitForOnline(ctx, conn, config.UseDatabase, config.Retry.RetryDelay, logger); err != nil {
+			return err
+		}
+	}
+
+	if config.MinVCores > 0 {
+		if err := WaitForMinVCores(ctx, conn, config.UseDatabase, config.MinVCores, config.Retry.RetryDelay, logger); err != nil {
+			return err
+		}
+	}
+
+	if config.VerifyIdentity {
+		identity, err := ReadIdentity(ctx, conn)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Connected to server '%s', database '%s'.", identity.ServerName, identity.DatabaseName))
+		result.Identity = &identity
+	}
+
+	// The database is awake, so a failure to warm up is not fatal
+	if config.WarmConnections > 0 {
+		logger.Info(fmt.Sprintf("Warming up with %d connections for %v.", config.WarmConnections, config.WarmDuration))
+		if err := WarmUp(ctx, conn, config.WarmConnections, config.WarmDuration, config.UseDatabase); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	return nil
+}
+
+const (
+	WAKEUP_USER               string = "WAKEUP_USER"
+	WAKEUP_PASSWORD           string = "WAKEUP_PASSWORD"
+	WAKEUP_SERVER             string = "WAKEUP_SERVER"
+	WAKEUP_INSTANCE           string = "WAKEUP_INSTANCE"
+	WAKEUP_DATABASE           string = "WAKEUP_DATABASE"
+	WAKEUP_DISCOVER_DATABASES string = "WAKEUP_DISCOVER_DATABASES"
+	WAKEUP_PORT               string = "WAKEUP_PORT"
+	WAKEUP_DSN                string = "WAKEUP_DSN"
+	WAKEUP_QUIET              string = "WAKEUP_QUIET"
+	WAKEUP_SILENT_SUCCESS     string = "WAKEUP_SILENT_SUCCESS"
+	WAKEUP_OUTPUT             string = "WAKEUP_OUTPUT"
+	WAKEUP_READ_ONLY          string = "WAKEUP_READ_ONLY"
+
+	WAKEUP_MULTI_SUBNET_FAILOVER   string = "WAKEUP_MULTI_SUBNET_FAILOVER"
+	WAKEUP_TCP_KEEPALIVE           string = "WAKEUP_TCP_KEEPALIVE"
+	WAKEUP_PROXY                   string = "WAKEUP_PROXY"
+	WAKEUP_EXEC_ON_WAKE            string = "WAKEUP_EXEC_ON_WAKE"
+	WAKEUP_ON_WAKE_URL             string = "WAKEUP_ON_WAKE_URL"
+	WAKEUP_ON_WAKE_METHOD          string = "WAKEUP_ON_WAKE_METHOD"
+	WAKEUP_ON_WAKE_HEADER          string = "WAKEUP_ON_WAKE_HEADER"
+	WAKEUP_ON_WAKE_BODY            string = "WAKEUP_ON_WAKE_BODY"
+	WAKEUP_ON_WAKE_EXIT_CODE       string = "WAKEUP_ON_WAKE_EXIT_CODE"
+	WAKEUP_INITIAL_CATALOG         string = "WAKEUP_INITIAL_CATALOG"
+	WAKEUP_WAIT_FOR_ONLINE         string = "WAKEUP_WAIT_FOR_ONLINE"
+	WAKEUP_MIN_VCORES              string = "WAKEUP_MIN_VCORES"
+	WAKEUP_RESUME_VIA_API          string = "WAKEUP_RESUME_VIA_API"
+	WAKEUP_SUBSCRIPTION_ID         string = "WAKEUP_SUBSCRIPTION_ID"
+	WAKEUP_RESOURCE_GROUP          string = "WAKEUP_RESOURCE_GROUP"
+	WAKEUP_AZURE_SERVER            string = "WAKEUP_AZURE_SERVER"
+	WAKEUP_TIMEOUT                 string = "WAKEUP_TIMEOUT"
+	WAKEUP_ATTEMPT_TIMEOUT         string = "WAKEUP_ATTEMPT_TIMEOUT"
+	WAKEUP_NO_JITTER               string = "WAKEUP_NO_JITTER"
+	WAKEUP_JITTER_PERCENT          string = "WAKEUP_JITTER_PERCENT"
+	WAKEUP_MAX_LOGIN_RATE          string = "WAKEUP_MAX_LOGIN_RATE"
+	WAKEUP_ENV_FILE                string = "WAKEUP_ENV_FILE"
+	WAKEUP_PROFILE                 string = "WAKEUP_PROFILE"
+	WAKEUP_ENV_PREFIX              string = "WAKEUP_ENV_PREFIX"
+	WAKEUP_PROGRESS                string = "WAKEUP_PROGRESS"
+	WAKEUP_APP_NAME_TEMPLATE       string = "WAKEUP_APP_NAME_TEMPLATE"
+	WAKEUP_FAIL_FAST               string = "WAKEUP_FAIL_FAST"
+	WAKEUP_CORRELATION_ID          string = "WAKEUP_CORRELATION_ID"
+	WAKEUP_REPORT_FILE             string = "WAKEUP_REPORT_FILE"
+	WAKEUP_PING_QUERY              string = "WAKEUP_PING_QUERY"
+	WAKEUP_PRINT_SCHEDULE          string = "WAKEUP_PRINT_SCHEDULE"
+	WAKEUP_MAX_ELAPSED             string = "WAKEUP_MAX_ELAPSED"
+	WAKEUP_ABORT_ON                string = "WAKEUP_ABORT_ON"
+	WAKEUP_VERIFY_IDENTITY         string = "WAKEUP_VERIFY_IDENTITY"
+	WAKEUP_PASSWORD_KEYVAULT       string = "WAKEUP_PASSWORD_KEYVAULT"
+	WAKEUP_AUTH                    string = "WAKEUP_AUTH"
+	WAKEUP_CLIENT_ID               string = "WAKEUP_CLIENT_ID"
+	WAKEUP_TOKEN_FILE              string = "WAKEUP_TOKEN_FILE"
+	WAKEUP_DISABLE_DRIVER_RETRY    string = "WAKEUP_DISABLE_DRIVER_RETRY"
+	WAKEUP_DRIVER_OPTIONS          string = "WAKEUP_DRIVER_OPTIONS"
+	WAKEUP_LISTEN                  string = "WAKEUP_LISTEN"
+	WAKEUP_STARTUP_JITTER          string = "WAKEUP_STARTUP_JITTER"
+	WAKEUP_PRINT_CONNECTION_STRING string = "WAKEUP_PRINT_CONNECTION_STRING"
+	WAKEUP_SUCCESS_MESSAGE         string = "WAKEUP_SUCCESS_MESSAGE"
+	WAKEUP_VERIFY_STABLE           string = "WAKEUP_VERIFY_STABLE"
+	WAKEUP_METRICS                 string = "WAKEUP_METRICS"
+	WAKEUP_LOCAL_ADDR              string = "WAKEUP_LOCAL_ADDR"
+	WAKEUP_VERIFY_RETRIES          string = "WAKEUP_VERIFY_RETRIES"
+	WAKEUP_ENDPOINT_TYPE           string = "WAKEUP_ENDPOINT_TYPE"
+	WAKEUP_TARGET                  string = "WAKEUP_TARGET"
+	WAKEUP_EVENT_SOCKET            string = "WAKEUP_EVENT_SOCKET"
+	WAKEUP_ENCRYPT                 string = "WAKEUP_ENCRYPT"
+	WAKEUP_NO_LINT                 string = "WAKEUP_NO_LINT"
+	WAKEUP_WARM_CONNECTIONS        string = "WAKEUP_WARM_CONNECTIONS"
+	WAKEUP_WARM_DURATION           string = "WAKEUP_WARM_DURATION"
+	WAKEUP_PROTOCOL                string = "WAKEUP_PROTOCOL"
+	WAKEUP_TRACE                   string = "WAKEUP_TRACE"
+	WAKEUP_TIMING                  string = "WAKEUP_TIMING"
+	WAKEUP_READINESS_QUERIES       string = "WAKEUP_READINESS_QUERIES"
+	WAKEUP_LOCK_FILE               string = "WAKEUP_LOCK_FILE"
+	WAKEUP_LOCK_MODE               string = "WAKEUP_LOCK_MODE"
+	WAKEUP_APP_CONFIG              string = "WAKEUP_APP_CONFIG"
+	WAKEUP_STATE_FILE              string = "WAKEUP_STATE_FILE"
+	WAKEUP_COOLDOWN                string = "WAKEUP_COOLDOWN"
+	WAKEUP_PARAMS                  string = "WAKEUP_PARAMS"
+	WAKEUP_DIAL_TIMEOUT            string = "WAKEUP_DIAL_TIMEOUT"
+	WAKEUP_ALERT_IF_PAUSED         string = "WAKEUP_ALERT_IF_PAUSED"
+	WAKEUP_TLS_MIN_VERSION         string = "WAKEUP_TLS_MIN_VERSION"
+	WAKEUP_SHARED_BUDGET           string = "WAKEUP_SHARED_BUDGET"
+	WAKEUP_SECRET                  string = "WAKEUP_SECRET"
+	WAKEUP_ITERATIONS              string = "WAKEUP_ITERATIONS"
+	WAKEUP_WAIT_FOR_PAUSE          string = "WAKEUP_WAIT_FOR_PAUSE"
+	WAKEUP_PAUSE_TIMEOUT           string = "WAKEUP_PAUSE_TIMEOUT"
+)
+
+// Version of this tool, set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// Ensure a connection with an Azure DB that may be auto-paused.
+// Wait and try for 5 minutes (by default) to wake it up.
+func main() {
+	log.SetOutput(os.Stderr)
+
+	// Without a command, the arguments are for wakeup
+	command, args := "wakeup", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	// Resolved before the commands register their flags, because the environment provides the flag defaults
+	if prefix := argValue(args, "env-prefix", os.Getenv(WAKEUP_ENV_PREFIX)); prefix != "" {
+		envPrefix = strings.TrimSuffix(prefix, "_")
+	}
+	envFile, profile := argValue(args, "env-file", GetEnv(WAKEUP_ENV_FILE, "")), argValue(args, "profile", GetEnv(WAKEUP_PROFILE, ""))
+	if envFile != "" {
+		if err := LoadEnvFile(envFile, profile); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	} else if profile != "" {
+		log.Fatalf("error: --profile selects a section of the --env-file, but no env file was given")
+	}
+	if endpoint := argValue(args, "app-config", GetEnv(WAKEUP_APP_CONFIG, "")); endpoint != "" {
+		credential, err := NewAzureCredential(argValue(args, "client-id", GetEnv(WAKEUP_CLIENT_ID, "")))
+		if err != nil {
+			log.Fatalf("error: %v: no Azure credential: %v", ErrAppConfig, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err = LoadAppConfig(ctx, credential, endpoint)
+		cancel()
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+
+	switch command {
+	case "wakeup":
+		os.Exit(runWakeup(args))
+	case "check":
+		os.Exit(runCheck(args))
+	case "serve":
+		os.Exit(runServe(args))
+	case "benchmark":
+		os.Exit(runBenchmark(args))
+	case "version":
+		os.Exit(runVersion(args))
+	default:
+		log.Fatalf("error: unknown command '%s', use wakeup, check, serve, benchmark or version", command)
+	}
 }
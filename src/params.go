@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Connection string keys known to the driver. Any other key may be a typo, but is still passed on.
+var knownParams = []string{
+	msdsn.Server, msdsn.Port, msdsn.Database, msdsn.UserID, msdsn.Password, msdsn.AppName,
+	msdsn.Encrypt, msdsn.TrustServerCertificate, msdsn.Certificate, msdsn.HostNameInCertificate, msdsn.TLSMin,
+	msdsn.ChangePassword, msdsn.PacketSize, msdsn.LogParam, msdsn.ConnectionTimeout, msdsn.DialTimeout,
+	msdsn.KeepAlive, msdsn.ServerSpn, msdsn.WorkstationID, msdsn.ApplicationIntent, msdsn.FailoverPartner,
+	msdsn.FailOverPort, msdsn.DisableRetry, msdsn.Protocol, msdsn.Pipe, msdsn.MultiSubnetFailover, msdsn.NoTraceID,
+	"columnencryption",
+}
+
+// Alternative names of keys in ADO connection strings, like the driver accepts them.
+var paramSynonyms = map[string]string{
+	"application name":          msdsn.AppName,
+	"data source":               msdsn.Server,
+	"address":                   msdsn.Server,
+	"network address":           msdsn.Server,
+	"addr":                      msdsn.Server,
+	"user":                      msdsn.UserID,
+	"uid":                       msdsn.UserID,
+	"pwd":                       msdsn.Password,
+	"initial catalog":           msdsn.Database,
+	"column encryption setting": "columnencryption",
+}
+
+// Parse ADO-style connection parameters, like "Server=myserver;User ID=app;Password='se;cret'".
+// Keys are case-insensitive, and synonyms like "Initial Catalog" are replaced by the key of the driver.
+// A value may be quoted with ' or ", to contain a ';'. A quote inside is escaped by doubling it, like:
+//
+//	Password='it''s'
+//
+// The keys that are not known to the driver are also returned, to warn about typos.
+func ParseConnectionParams(params string) (map[string]string, []string, error) {
+	parsed := map[string]string{}
+	var unknown []string
+	for rest := params; strings.Trim(rest, " ;") != ""; {
+		rest = strings.TrimLeft(rest, " ;")
+		key, after, found := strings.Cut(rest, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found || key == "" || strings.Contains(key, ";") {
+			pair, _, _ := strings.Cut(rest, ";")
+			return nil, nil, fmt.Errorf("invalid connection parameter '%s', use key=value", strings.TrimSpace(pair))
+		}
+
+		var value string
+		var err error
+		value, rest, err = cutParamValue(key, strings.TrimLeft(after, " "))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if synonym, ok := paramSynonyms[key]; ok {
+			key = synonym
+		}
+		if _, seen := parsed[key]; !seen && !slices.Contains(knownParams, key) {
+			unknown = append(unknown, key)
+		}
+		parsed[key] = value
+	}
+	return parsed, unknown, nil
+}
+
+// Cut the value of a parameter from the start of s, and return the text after it.
+func cutParamValue(key, s string) (string, string, error) {
+	if s == "" || (s[0] != '\'' && s[0] != '"') {
+		value, rest, _ := strings.Cut(s, ";")
+		return strings.TrimSpace(value), rest, nil
+	}
+
+	quote := s[0]
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			value.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			value.WriteByte(quote)
+			i++
+			continue
+		}
+
+		rest := strings.TrimLeft(s[i+1:], " ")
+		if rest != "" && rest[0] != ';' {
+			return "", "", fmt.Errorf("unexpected text after the quoted value of connection parameter '%s'", key)
+		}
+		return value.String(), rest, nil
+	}
+	return "", "", fmt.Errorf("missing closing quote in the value of connection parameter '%s'", key)
+}
+
+// A database= or initial catalog= pair in a key=value; connection string, with its leading ';'.
+// The driver does not unquote these values, so they end at the first ';'.
+var databasePair = regexp.MustCompile(`(?i)(?:^|;)\s*(?:database|initial catalog)\s*=[^;]*`)
+
+// The same pair in an ODBC connection string, where the value may be {braced}.
+var odbcDatabasePair = regexp.MustCompile(`(?i)(?:^|;)\s*(?:database|initial catalog)\s*=\s*(?:\{(?:[^}]|\}\})*\}|[^;]*)`)
+
+// Replace the database of a connection string, whether the DSN named it with database= or initial catalog=, or not at all.
+// Supports the sqlserver:// URL form, and the key=value; form (also with an odbc: prefix). The result always uses database=.
+func SetDSNDatabase(dsn, database string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(dsn), "sqlserver://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid DSN: %v", RedactConnectionString(err.Error()))
+		}
+		q := u.Query()
+		for key := range q {
+			if strings.EqualFold(key, "database") || strings.EqualFold(key, "initial catalog") {
+				q.Del(key)
+			}
+		}
+		q.Set("database", database)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	var prefix, value string
+	if strings.HasPrefix(strings.ToLower(dsn), "odbc:") {
+		prefix, dsn = dsn[:len("odbc:")], odbcDatabasePair.ReplaceAllString(dsn[len("odbc:"):], "")
+		value = "{" + strings.ReplaceAll(database, "}", "}}") + "}"
+	} else {
+		if strings.Contains(database, ";") || strings.TrimSpace(database) != database {
+			return "", fmt.Errorf("database '%s' cannot be set in this DSN, use the sqlserver:// or odbc: form", database)
+		}
+		dsn, value = databasePair.ReplaceAllString(dsn, ""), database
+	}
+
+	dsn = strings.Trim(dsn, "; ")
+	if dsn == "" {
+		return prefix + "database=" + value, nil
+	}
+	return prefix + dsn + ";database=" + value, nil
+}
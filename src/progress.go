@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Single-line progress indicator for a terminal, redrawn while waiting between attempts.
+type Progress struct {
+	w          io.Writer
+	color      bool
+	maxRetries int
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Whether a progress indicator can be drawn on a file: only if it is a terminal.
+func CanShowProgress(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Create a progress indicator. Color is used unless NO_COLOR is set or the terminal is dumb.
+func NewProgress(w io.Writer, maxRetries int) *Progress {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &Progress{
+		w:          w,
+		color:      !noColor && os.Getenv("TERM") != "dumb",
+		maxRetries: maxRetries,
+	}
+}
+
+// Show a countdown until the next attempt. Fits RetryConfig.OnRetry.
+func (p *Progress) OnRetry(attempt int, delay time.Duration, _ error) {
+	p.Done()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		deadline := time.Now().Add(delay)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for frame := 0; ; frame++ {
+			spinner := spinnerFrames[frame%len(spinnerFrames)]
+			if remaining := time.Until(deadline).Round(time.Second); remaining > 0 {
+				p.draw(spinner, fmt.Sprintf("waiting for database to wake (attempt %d/%d, next retry in %v)", attempt-1, p.maxRetries, remaining))
+			} else {
+				p.draw(spinner, fmt.Sprintf("waiting for database to wake (attempt %d/%d)", attempt, p.maxRetries))
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(p.stop, p.done)
+}
+
+// Stop the countdown and clear the line.
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	<-p.done
+	p.stop, p.done = nil, nil
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+func (p *Progress) draw(spinner, message string) {
+	if p.color {
+		spinner = "\033[36m" + spinner + "\033[0m"
+	}
+	fmt.Fprintf(p.w, "\r\033[K%s %s", spinner, message)
+}
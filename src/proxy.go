@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer that tunnels the TDS connection through a SOCKS5 proxy.
+// It is a mssql.HostDialer, so the database hostname is resolved by the proxy.
+type proxyDialer struct {
+	dialer proxy.ContextDialer
+	host   string
+}
+
+func (d proxyDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx, network, addr)
+}
+
+func (d proxyDialer) HostName() string {
+	return d.host
+}
+
+// The KeepAlive of a net.Dialer for a --tcp-keepalive interval: the driver default of 30 seconds if unset, and disabled for 0.
+// The driver only applies its keepAlive parameter to its own dialer, so a custom dialer must set it too.
+func dialerKeepAlive(interval *time.Duration) time.Duration {
+	switch {
+	case interval == nil:
+		return 30 * time.Second
+	case *interval == 0:
+		return -1
+	default:
+		return *interval
+	}
+}
+
+// Create a dialer whose connections originate from a local IP address, like "10.0.0.5", optionally with a port.
+// The keepAlive is the KeepAlive of the net.Dialer, like from dialerKeepAlive.
+func NewLocalDialer(localAddr string, keepAlive time.Duration) (*net.Dialer, error) {
+	host, port := localAddr, "0"
+	if h, p, err := net.SplitHostPort(localAddr); err == nil {
+		host, port = h, p
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local address '%s', use an IP address", localAddr)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 0 || p > 65535 {
+		return nil, fmt.Errorf("invalid port in local address '%s'", localAddr)
+	}
+
+	return &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip, Port: p}, KeepAlive: keepAlive}, nil
+}
+
+// Create a dialer from a socks5:// proxy URL. Proxy authentication is taken from the URL's user info.
+// The connection to the proxy is made with the forward dialer.
+func NewProxyDialer(proxyURL string, forward proxy.Dialer) (mssql.Dialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme '%s', use socks5://", u.Scheme)
+	}
+
+	d, err := proxy.FromURL(u, forward)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("proxy '%s' does not support dialing with a context", u.Host)
+	}
+
+	return proxyDialer{dialer: cd, host: u.Hostname()}, nil
+}
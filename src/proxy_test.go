@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLocalDialerKeepAlive(t *testing.T) {
+	minute, zero := time.Minute, time.Duration(0)
+	tests := []struct {
+		name     string
+		interval *time.Duration
+		want     time.Duration
+	}{
+		{"driver default", nil, 30 * time.Second},
+		{"interval", &minute, time.Minute},
+		{"disabled", &zero, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewLocalDialer("10.0.0.5", dialerKeepAlive(tt.interval))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d.KeepAlive != tt.want {
+				t.Errorf("got KeepAlive %v, want %v", d.KeepAlive, tt.want)
+			}
+			if d.LocalAddr.String() != "10.0.0.5:0" {
+				t.Errorf("got local address %v, want 10.0.0.5:0", d.LocalAddr)
+			}
+		})
+	}
+}
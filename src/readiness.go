@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-sql/sqlexp"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Returned when a database is reachable, but does not come online before the deadline.
+var ErrNotOnline = errors.New("database did not come online")
+
+// Poll the status of a database until it is ONLINE, pausing delay between polls.
+// If name is empty, the current database is polled.
+func WaitForOnline(ctx context.Context, db *sql.DB, name string, delay time.Duration, logger *slog.Logger) error {
+	var status sql.NullString
+	for {
+		var err error
+		if name != "" {
+			err = db.QueryRowContext(ctx, "SELECT CONVERT(nvarchar(60), DATABASEPROPERTYEX(@p1, 'Status'))", name).Scan(&status)
+		} else {
+			err = db.QueryRowContext(ctx, "SELECT CONVERT(nvarchar(60), DATABASEPROPERTYEX(DB_NAME(), 'Status'))").Scan(&status)
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("error querying database status: %v", err)
+		}
+
+		if status.String == "ONLINE" {
+			return nil
+		}
+		logger.Info(fmt.Sprintf("database status is '%s', waiting %v for it to come online", status.String, delay))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: last status '%s'", ErrNotOnline, status.String)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Returned when the compute of a database does not reach the minimum before the deadline.
+var ErrComputeNotReady = errors.New("compute did not reach the minimum vCores")
+
+// SQL errors when the resource governance DMV cannot be read: it does not exist outside Azure SQL (208),
+// or the principal lacks the VIEW DATABASE STATE permission (229, 297, 300).
+var governanceUnavailableNumbers = []int32{208, 229, 297, 300}
+
+// Poll the vCores allocated to a database until there are at least minVCores, pausing delay between polls.
+// A serverless database resumes at its minimum vCores and scales up under load, which cpu_limit of
+// sys.dm_user_db_resource_governance follows. If name is empty, the current database is polled.
+// If the DMV cannot be read, like without the permission, the check is skipped with a warning, as the database itself is awake.
+func WaitForMinVCores(ctx context.Context, db *sql.DB, name string, minVCores float64, delay time.Duration, logger *slog.Logger) error {
+	var vCores sql.NullFloat64
+	for {
+		var err error
+		if name != "" {
+			err = db.QueryRowContext(ctx, "SELECT CONVERT(float, cpu_limit) FROM sys.dm_user_db_resource_governance WHERE database_id = DB_ID(@p1)", name).Scan(&vCores)
+		} else {
+			err = db.QueryRowContext(ctx, "SELECT CONVERT(float, cpu_limit) FROM sys.dm_user_db_resource_governance WHERE database_id = DB_ID()").Scan(&vCores)
+		}
+		if number, ok := sqlErrorNumber(err); (ok && slices.Contains(governanceUnavailableNumbers, number)) || errors.Is(err, sql.ErrNoRows) {
+			logger.Warn(fmt.Sprintf("skipping the check for %v vCores, as the resource governance of the database cannot be read: %v", minVCores, err))
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("error querying allocated vCores: %v", err)
+		}
+
+		if vCores.Valid && vCores.Float64 >= minVCores {
+			logger.Info(fmt.Sprintf("Database has %v vCores, at least the minimum of %v.", vCores.Float64, minVCores))
+			return nil
+		}
+		logger.Info(fmt.Sprintf("database has %v of the minimum %v vCores, waiting %v for it to scale up", vCores.Float64, minVCores, delay))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: last %v of %v vCores", ErrComputeNotReady, vCores.Float64, minVCores)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Read which server and database a connection actually landed on, like "myserver" and "general".
+func ReadIdentity(ctx context.Context, db *sql.DB) (Identity, error) {
+	var identity Identity
+	var serverName sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT @@SERVERNAME, DB_NAME()").Scan(&serverName, &identity.DatabaseName)
+	if err != nil {
+		return identity, fmt.Errorf("error reading server identity: %w", err)
+	}
+	identity.ServerName = serverName.String
+	return identity, nil
+}
+
+// Hold n concurrent connections that run SELECT 1 in a loop for duration, to nudge the autoscaler of a serverless database.
+// If name is not empty, each connection switches to that database first. All connections are closed on return.
+func WarmUp(ctx context.Context, db *sql.DB, n int, duration time.Duration, name string) error {
+	db.SetMaxOpenConns(n)
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = warmConnection(ctx, db, name)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("error warming up: %w", err)
+	}
+	return nil
+}
+
+// Run SELECT 1 on a single connection until ctx is done, which is the expected way to stop.
+func warmConnection(ctx context.Context, db *sql.DB, name string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return ignoreDone(ctx, err)
+	}
+	defer conn.Close()
+
+	if name != "" {
+		if _, err := conn.ExecContext(ctx, "USE "+mssql.TSQLQuoter{}.ID(name)); err != nil {
+			return ignoreDone(ctx, err)
+		}
+	}
+	for ctx.Err() == nil {
+		if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+			return ignoreDone(ctx, err)
+		}
+	}
+	return nil
+}
+
+// An error caused by the end of the warm-up duration is not a failure.
+func ignoreDone(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Run a query and log the informational messages of the server, from PRINT or RAISERROR with a low severity, as debug records.
+// The first error of the server is returned, like from ExecContext. Any rows are discarded.
+func ExecWithMessages(ctx context.Context, db *sql.DB, query string, logger *slog.Logger) error {
+	messages := &sqlexp.ReturnMessage{}
+	rows, err := db.QueryContext(ctx, query, messages)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// With a ReturnMessage, errors of the server are messages too
+	var firstErr error
+	for active := true; active; {
+		switch m := messages.Message(ctx).(type) {
+		case sqlexp.MsgNotice:
+			logger.Debug(fmt.Sprintf("Server message: %s", m.Message))
+		case sqlexp.MsgError:
+			if firstErr == nil {
+				firstErr = m.Error
+			}
+		case sqlexp.MsgNext:
+			for rows.Next() {
+			}
+		case sqlexp.MsgNextResultSet:
+			active = rows.NextResultSet()
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// Names of connection string parameters whose values are secrets, in lower case.
+var secretParams = []string{"password", "pwd", "access token", "accesstoken", "client secret", "clientsecret", "keystoresecret"}
+
+// Whether the value of a connection string parameter is a secret. The name is case-insensitive.
+func isSecretParam(name string) bool {
+	return slices.Contains(secretParams, strings.Join(strings.Fields(strings.ToLower(name)), " "))
+}
+
+// A secret pair, like password=, in a key=value; connection string. The value may be {braced}, quoted or plain.
+var passwordPair = regexp.MustCompile(`(?i)((?:^odbc:|^|;)\s*(?:` + secretParamPattern() + `)\s*=\s*)(\{(?:[^}]|\}\})*\}|"(?:[^"]|"")*"|'(?:[^']|'')*'|[^;]*)`)
+
+// The secretParams as alternatives of a regular expression, with any whitespace inside a name.
+func secretParamPattern() string {
+	var names []string
+	for _, name := range secretParams {
+		names = append(names, strings.ReplaceAll(regexp.QuoteMeta(name), " ", `\s+`))
+	}
+	return strings.Join(names, "|")
+}
+
+// Mask the password in a connection string, so it can be logged.
+// Supports the sqlserver:// URL form, and the key=value; form (also with an odbc: prefix).
+func RedactConnectionString(s string) string {
+	if !strings.HasPrefix(strings.ToLower(s), "sqlserver://") {
+		return passwordPair.ReplaceAllString(s, "${1}"+redacted)
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		// Should not happen for a connection string that was accepted, but never leak it
+		return "sqlserver://" + redacted
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+
+	q := u.Query()
+	for key := range q {
+		if isSecretParam(key) {
+			q.Set(key, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// Names of HTTP headers and query parameters whose values are secrets, like an Authorization header or the code of an Azure Function URL.
+var secretName = regexp.MustCompile(`(?i)auth|cookie|key|token|secret|password|signature|^sig$|^code$`)
+
+// Mask the password and secret query parameters of a URL, so it can be logged.
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+
+	q := u.Query()
+	for key := range q {
+		if secretName.MatchString(key) {
+			q.Set(key, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.Redacted()
+}
+
+// Format an HTTP header as "Name: value" for the logs, with the value masked if the name suggests a secret.
+func RedactHeader(name, value string) string {
+	if secretName.MatchString(name) {
+		value = redacted
+	}
+	return name + ": " + value
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Write a report with a row per database to path: CSV for the text format, the result document for JSON.
+// The report is replaced atomically, so it is never read while partially written.
+func WriteReportFile(path, format string, results []Result) error {
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		if format == OUTPUT_JSON {
+			return WriteResults(w, format, "", results)
+		}
+		return writeReportCSV(w, results)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing report file: %v", err)
+	}
+	return nil
+}
+
+// Write a file to a temporary file that is renamed to path, so readers see either the old or the new content.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+
+	// CreateTemp makes the file private, but it is for other processes to read
+	err = tmp.Chmod(0o644)
+	if err == nil {
+		err = write(tmp)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Write a row per server a database was tried on, so the row of the server that woke it has success true.
+func writeReportCSV(w io.Writer, results []Result) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"server", "database", "success", "attempts", "elapsed_ms", "throttled", "timed_out", "error"})
+	for _, r := range results {
+		servers := r.Servers
+		if len(servers) == 0 {
+			servers = []ServerResult{{r.Server, r.Success, r.Attempts, r.ElapsedMs, r.Throttled, r.TimedOut, r.Error}}
+		}
+		for _, s := range servers {
+			msg := ""
+			if s.Error != nil {
+				msg = *s.Error
+			}
+			c.Write([]string{
+				s.Server,
+				r.Database,
+				strconv.FormatBool(s.Success),
+				strconv.Itoa(s.Attempts),
+				strconv.FormatInt(s.ElapsedMs, 10),
+				strconv.FormatBool(s.Throttled),
+				strconv.FormatBool(s.TimedOut),
+				msg,
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+)
+
+func TestWriteReportCSV(t *testing.T) {
+	msg := "login timed out"
+	results := []Result{
+		{Success: true, Server: "dr", Database: "sales", Servers: []ServerResult{
+			{Server: "primary", Attempts: 2, ElapsedMs: 1500, TimedOut: true, Error: &msg},
+			{Server: "dr", Success: true, Attempts: 1, ElapsedMs: 200},
+		}},
+		{Server: "primary", Database: "hr", Attempts: 1, Throttled: true, Error: &msg},
+	}
+
+	var buf bytes.Buffer
+	if err := writeReportCSV(&buf, results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+
+	want := [][]string{
+		{"server", "database", "success", "attempts", "elapsed_ms", "throttled", "timed_out", "error"},
+		{"primary", "sales", "false", "2", "1500", "false", "true", msg},
+		{"dr", "sales", "true", "1", "200", "false", "false", ""},
+		// Without servers, like a result from before the fallback servers, the result is its own row
+		{"primary", "hr", "false", "1", "0", "true", "false", msg},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d: got %v, want %v", i, rows[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OUTPUT_TEXT string = "text"
+	OUTPUT_JSON string = "json"
+)
+
+// Exit codes of a failed run. The flag package exits with 2 on unknown flags.
+const (
+	EXIT_FAILURE   int = 1
+	EXIT_THROTTLED int = 3
+	EXIT_RESUMED   int = 4   // Woke up a database that was paused, with --alert-if-paused
+	EXIT_COOLDOWN  int = 5   // Skipped, as the last run started within the --cooldown
+	EXIT_ABORTED   int = 6   // Failed on an error number of --abort-on
+	EXIT_CALLBACK  int = 7   // The --on-wake-url request failed, unless --on-wake-exit-code is set
+	EXIT_LOCKED    int = 75  // Like EX_TEMPFAIL: another run holds the --lock-file
+	EXIT_TIMEOUT   int = 124 // Like timeout(1)
+)
+
+// Exit codes of failed results, from least to most severe: a run with multiple results exits with the most severe.
+var exitCodePrecedence = []int{EXIT_THROTTLED, EXIT_TIMEOUT, EXIT_FAILURE, EXIT_ABORTED}
+
+// A first attempt that takes longer than this likely waited for a resume. A variable, so tests can shorten it.
+var alreadyAwakeThreshold = 5 * time.Second
+
+// Outcome of waking up a single database.
+type Result struct {
+	Success      bool      `json:"success"`
+	Server       string    `json:"server"`
+	Database     string    `json:"database"`
+	Attempts     int       `json:"attempts"`
+	ElapsedMs    int64     `json:"elapsed_ms"`
+	Throttled    bool      `json:"throttled"`     // Failed because the database was (still) unavailable
+	TimedOut     bool      `json:"timed_out"`     // Failed because the time ran out
+	Aborted      bool      `json:"aborted"`       // Failed on an error number of --abort-on, without retrying it
+	AlreadyAwake bool      `json:"already_awake"` // The first connection attempt succeeded within alreadyAwakeThreshold: no resume needed
+	Identity     *Identity `json:"identity,omitempty"`
+	Timing       *Timing   `json:"timing,omitempty"` // With --timing, of the successful attempt
+	Error        *string   `json:"error"`
+	SQLError     *SQLError `json:"sql_error,omitempty"` // Only on failure with an error from the server
+
+	// The servers tried, in order: the primary and the fallbacks until one succeeded
+	Servers []ServerResult `json:"servers"`
+
+	// The connection string of the server that woke the database, with its password: never printed
+	connectionString string
+
+	// How long the connection attempts on the last server took, without the steps after connecting (like --warm-connections)
+	connectElapsed time.Duration
+}
+
+// Outcome of waking up a database on one of its servers. The successful server is the one that woke it.
+type ServerResult struct {
+	Server    string  `json:"server"`
+	Success   bool    `json:"success"`
+	Attempts  int     `json:"attempts"`
+	ElapsedMs int64   `json:"elapsed_ms"`
+	Throttled bool    `json:"throttled"`
+	TimedOut  bool    `json:"timed_out"`
+	Error     *string `json:"error"`
+}
+
+// The raw error reported by the server, for scripts that act on specific error numbers.
+type SQLError struct {
+	Number  int32  `json:"number"`
+	Line    int32  `json:"line"`
+	Message string `json:"message"`
+}
+
+// Log attributes of the SQL error of a failed result, if any.
+func (r Result) sqlErrorAttrs() []any {
+	if r.SQLError == nil {
+		return nil
+	}
+	return []any{"sql_error_number", r.SQLError.Number, "sql_error_line", r.SQLError.Line}
+}
+
+// The exit code for a result: 0 on success.
+// Running out of time takes precedence over throttling, as the database may just need longer to resume.
+func (r Result) ExitCode() int {
+	switch {
+	case r.Success:
+		return 0
+	case r.Aborted:
+		return EXIT_ABORTED
+	case r.TimedOut:
+		return EXIT_TIMEOUT
+	case r.Throttled:
+		return EXIT_THROTTLED
+	default:
+		return EXIT_FAILURE
+	}
+}
+
+// The server and database a connection landed on, as reported by the server itself.
+type Identity struct {
+	ServerName   string `json:"server_name"` // @@SERVERNAME, empty if unknown
+	DatabaseName string `json:"database_name"`
+}
+
+// Write the final result document. A single result is written as an object, multiple results as an array.
+// In the text format, a successMessage template with {server}, {database}, {attempts} and {elapsed} replaces the default message.
+func WriteResults(w io.Writer, format, successMessage string, results []Result) error {
+	switch format {
+	case OUTPUT_JSON:
+		enc := json.NewEncoder(w)
+		if len(results) == 1 {
+			return enc.Encode(results[0])
+		}
+		return enc.Encode(results)
+	case OUTPUT_TEXT:
+		for _, r := range results {
+			if !r.Success {
+				continue
+			}
+			if successMessage != "" {
+				fmt.Fprintln(w, ExpandTemplate(successMessage, r.templateValues()))
+			} else if len(results) == 1 {
+				fmt.Fprintln(w, "Connection successful: database is awake.")
+			} else {
+				fmt.Fprintf(w, "Connection successful: database '%s' is awake.\n", r.Database)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+// The {server}, {database}, {attempts} and {elapsed} placeholders of the templates of a result.
+func (r Result) templateValues() map[string]string {
+	return map[string]string{
+		"server":   r.Server,
+		"database": r.Database,
+		"attempts": strconv.Itoa(r.Attempts),
+		"elapsed":  (time.Duration(r.ElapsedMs) * time.Millisecond).String(),
+	}
+}
+
+// Write a key=value metrics line per result, like "wakeup_success=1 wakeup_attempts=2 wakeup_duration_seconds=36.1 server=... database=...".
+// Values with spaces or quotes are quoted, so the line stays easy to split with grep or awk.
+func WriteMetrics(w io.Writer, results []Result) {
+	for _, r := range results {
+		success := 0
+		if r.Success {
+			success = 1
+		}
+		fmt.Fprintf(w, "wakeup_success=%d wakeup_attempts=%d wakeup_duration_seconds=%.1f server=%s database=%s\n",
+			success, r.Attempts, float64(r.ElapsedMs)/1000, metricsValue(r.Server), metricsValue(r.Database))
+	}
+}
+
+func metricsValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// Count the results of a batch, like "12 databases: 10 already awake, 1 resumed, 1 failed".
+func Summary(results []Result) string {
+	var awake, resumed, failed int
+	for _, r := range results {
+		switch {
+		case !r.Success:
+			failed++
+		case r.AlreadyAwake:
+			awake++
+		default:
+			resumed++
+		}
+	}
+	return fmt.Sprintf("%d databases: %d already awake, %d resumed, %d failed", len(results), awake, resumed, failed)
+}
+
+// Whether a database of a batch had to be resumed, for --alert-if-paused.
+func AnyResumed(results []Result) bool {
+	return slices.ContainsFunc(results, func(r Result) bool { return !r.AlreadyAwake })
+}
+
+// Outcome of checking a single database.
+type CheckResult struct {
+	Server   string  `json:"server"`
+	Database string  `json:"database"`
+	Status   string  `json:"status,omitempty"` // From the Azure management API, like "Online" or "Paused"
+	Error    *string `json:"error"`
+}
+
+// Write the check result document. A single result is written as an object, multiple results as an array.
+func WriteCheckResults(w io.Writer, format string, results []CheckResult) error {
+	switch format {
+	case OUTPUT_JSON:
+		enc := json.NewEncoder(w)
+		if len(results) == 1 {
+			return enc.Encode(results[0])
+		}
+		return enc.Encode(results)
+	case OUTPUT_TEXT:
+		for _, r := range results {
+			if r.Error != nil {
+				continue
+			}
+			if r.Status != "" {
+				fmt.Fprintf(w, "Database '%s' on '%s' is %s.\n", r.Database, r.Server, r.Status)
+			} else {
+				fmt.Fprintf(w, "Connection details for database '%s' on '%s' are valid.\n", r.Database, r.Server)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format '%s'", format)
+	}
+}
+
+// Write the planned pause before each attempt and the cumulative wait, and whether the time budget cuts the schedule short.
+func WriteSchedule(w io.Writer, schedule []time.Duration, budget time.Duration) {
+	var total time.Duration
+	for i, delay := range schedule {
+		total += delay
+		fmt.Fprintf(w, "attempt %d/%d after %v delay (total wait %v)\n", i+1, len(schedule), delay, total)
+	}
+	if total > budget {
+		fmt.Fprintf(w, "The total wait exceeds the time budget of %v: later attempts will not be made.\n", budget)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteResultsSQLError(t *testing.T) {
+	msg := "mssql: Database 'db' on server 'srv' is not currently available."
+	tests := []struct {
+		name   string
+		result Result
+		want   map[string]any
+	}{
+		{"failure", Result{Server: "srv", Database: "db", Error: &msg, SQLError: &SQLError{Number: 40613, Line: 1, Message: msg}},
+			map[string]any{"number": float64(40613), "line": float64(1), "message": msg}},
+		{"success", Result{Success: true, Server: "srv", Database: "db"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResults(&buf, OUTPUT_JSON, "", []Result{tt.result}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("invalid JSON '%s': %v", buf.String(), err)
+			}
+
+			got, found := doc["sql_error"].(map[string]any)
+			if tt.want == nil {
+				if _, found := doc["sql_error"]; found {
+					t.Errorf("got sql_error %v, want none", doc["sql_error"])
+				}
+				return
+			}
+			if !found {
+				t.Fatalf("no sql_error in '%s'", buf.String())
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("got sql_error.%s %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestResultExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   int
+	}{
+		{"success", Result{Success: true}, 0},
+		{"failure", Result{}, EXIT_FAILURE},
+		{"throttled", Result{Throttled: true}, EXIT_THROTTLED},
+		{"timed out", Result{TimedOut: true}, EXIT_TIMEOUT},
+		{"timed out while throttled", Result{Throttled: true, TimedOut: true}, EXIT_TIMEOUT},
+		{"aborted", Result{Aborted: true, Throttled: true}, EXIT_ABORTED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.ExitCode(); got != tt.want {
+				t.Errorf("got exit code %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteResultsMatrix(t *testing.T) {
+	msg := "login timed out"
+	results := []Result{
+		{Success: true, Server: "dr", Database: "sales", Attempts: 3, Servers: []ServerResult{
+			{Server: "primary", Attempts: 2, TimedOut: true, Error: &msg},
+			{Server: "dr", Success: true, Attempts: 1},
+		}},
+		{Success: true, Server: "primary", Database: "hr", Attempts: 1, Servers: []ServerResult{
+			{Server: "primary", Success: true, Attempts: 1},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OUTPUT_JSON, "", results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc []Result
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON '%s': %v", buf.String(), err)
+	}
+	if len(doc) != 2 {
+		t.Fatalf("got %d results, want an array of 2", len(doc))
+	}
+	if len(doc[0].Servers) != 2 || doc[0].Servers[0].Success || !doc[0].Servers[1].Success {
+		t.Errorf("got servers %+v, want the fallback server flagged as the one that woke 'sales'", doc[0].Servers)
+	}
+	if len(doc[1].Servers) != 1 || !doc[1].Servers[0].Success {
+		t.Errorf("got servers %+v, want the primary server flagged as the one that woke 'hr'", doc[1].Servers)
+	}
+}
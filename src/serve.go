@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Serve POST /wakeup, to wake up the databases on demand. Runs the wake-ups one request at a time.
+func runServe(args []string) int {
+	fs := newFlagSet("serve", `Serve an HTTP endpoint that wakes up the databases on demand.
+
+  POST /wakeup runs a wake-up and responds with the JSON result: 200 OK on success, 503 Service Unavailable otherwise.
+  With --secret, requests need an "Authorization: Bearer <secret>" header.`)
+	conn := addConnectionFlags(fs)
+	out := addOutputFlags(fs)
+	attempt := addAttemptFlags(fs)
+	listen := fs.String("listen", GetEnv(WAKEUP_LISTEN, ":8080"), "Address to listen on")
+	secret := fs.String("secret", GetEnv(WAKEUP_SECRET, ""), "Shared secret that requests must send as bearer token (default: none)")
+
+	parseFlags(fs, out, args)
+
+	logger, err := out.logger()
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	logger, err = conn.withCorrelationID(logger)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	configs, err := attempt.configs(conn, logger)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	for i := range configs {
+		configs[i].Trace = *out.trace
+	}
+
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /wakeup", func(w http.ResponseWriter, r *http.Request) {
+		if *secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+*secret)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Concurrent requests wait for the running wake-up, which then usually succeeds immediately
+		mu.Lock()
+		defer mu.Unlock()
+
+		logger.Info(fmt.Sprintf("Wake-up requested by %s.", r.RemoteAddr))
+		status := http.StatusOK
+		var results []Result
+		budget := attempt.newSharedBudget(len(configs))
+		for _, config := range configs {
+			if budget != nil {
+				config.Timeout = budget.Next()
+				config.SharedTimeout = true
+			}
+			result := Wakeup(config)
+			if !result.Success {
+				logger.Error(*result.Error, result.sqlErrorAttrs()...)
+				status = http.StatusServiceUnavailable
+			}
+			results = append(results, result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := WriteResults(w, OUTPUT_JSON, "", results); err != nil {
+			logger.Error(fmt.Sprintf("error writing response: %v", err))
+		}
+	})
+
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Info(fmt.Sprintf("Listening on %s.", *listen))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("error: %v", err)
+	}
+	return 0
+}
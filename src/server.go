@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_])?$`)
+
+// Normalize a server as pasted from other tools, like SSMS: strip a leading "tcp:", and split off a ",port" and "\instance".
+// So "tcp:myserver,1433" and "myserver\SQLEXPRESS,1433" are accepted. Parts in the server value take precedence over the given port and instance.
+func NormalizeServer(server, port, instance string) (string, string, string, error) {
+	host := strings.TrimSpace(server)
+	if host == "" {
+		return host, port, instance, nil
+	}
+
+	if len(host) > 4 && strings.EqualFold(host[:4], "tcp:") {
+		host = host[4:]
+	}
+
+	if h, p, found := strings.Cut(host, ","); found {
+		p = strings.TrimSpace(p)
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return "", "", "", fmt.Errorf("invalid port '%s' in server '%s'", p, server)
+		}
+		host, port = strings.TrimSpace(h), p
+	}
+
+	if h, i, found := strings.Cut(host, `\`); found {
+		if i = strings.TrimSpace(i); i == "" {
+			return "", "", "", fmt.Errorf("empty instance name in server '%s'", server)
+		}
+		host, instance = strings.TrimSpace(h), i
+	}
+
+	// IPv6 literals may be pasted with brackets, which are added back when joining with the port
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+
+	if net.ParseIP(host) == nil && !hostnamePattern.MatchString(host) {
+		return "", "", "", fmt.Errorf("invalid server hostname '%s'", server)
+	}
+
+	return host, port, instance, nil
+}
+
+// Split a comma-separated list of servers, like "primary.example.com,dr.example.com".
+// A numeric item is the SSMS-style port of the server before it, so "primary,1433,dr" is two servers.
+func SplitServers(servers string) []string {
+	var list []string
+	for _, item := range strings.Split(servers, ",") {
+		if _, err := strconv.Atoi(strings.TrimSpace(item)); err == nil && len(list) > 0 {
+			list[len(list)-1] += "," + item
+			continue
+		}
+		list = append(list, item)
+	}
+	return list
+}
@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestNormalizeServer(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		host   string
+		port   string
+	}{
+		{"plain", "myserver.database.windows.net", "myserver.database.windows.net", ""},
+		{"tcp prefix", "tcp:myserver.database.windows.net", "myserver.database.windows.net", ""},
+		{"tcp prefix in upper case", "TCP:myserver.database.windows.net", "myserver.database.windows.net", ""},
+		{"port suffix", "myserver.database.windows.net,1433", "myserver.database.windows.net", "1433"},
+		{"tcp prefix and port suffix", "tcp:myserver.database.windows.net,1433", "myserver.database.windows.net", "1433"},
+		{"spaces around the port", " myserver , 1434 ", "myserver", "1434"},
+		{"IPv4", "tcp:10.0.0.1,1433", "10.0.0.1", "1433"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, _, err := NormalizeServer(tt.server, "", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if host != tt.host || port != tt.port {
+				t.Errorf("got host '%s' and port '%s', want '%s' and '%s'", host, port, tt.host, tt.port)
+			}
+		})
+	}
+}
+
+func TestNormalizeServerInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+	}{
+		{"non-numeric port", "myserver,abc"},
+		{"port out of range", "myserver,70000"},
+		{"empty port", "myserver,"},
+		{"space in the hostname", "my server"},
+		{"URL instead of a hostname", "https://myserver.database.windows.net"},
+		{"only a tcp prefix and port", "tcp:,1433"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := NormalizeServer(tt.server, "", ""); err == nil {
+				t.Errorf("expected an error for server '%s'", tt.server)
+			}
+		})
+	}
+}
+
+func TestNormalizeServerSSMS(t *testing.T) {
+	tests := []struct {
+		name             string
+		server           string
+		port, instance   string
+		host             string
+		wantPort, wantIn string
+	}{
+		{"host and port", "myserver.database.windows.net,1433", "", "", "myserver.database.windows.net", "1433", ""},
+		{"instance", `SERVER\SQLEXPRESS`, "", "", "SERVER", "", "SQLEXPRESS"},
+		{"instance and port", `SERVER\INSTANCE,1433`, "", "", "SERVER", "1433", "INSTANCE"},
+		{"tcp prefix, instance and port", `tcp:SERVER\INSTANCE,1433`, "", "", "SERVER", "1433", "INSTANCE"},
+		{"explicit port and instance without parts", "SERVER", "1500", "OTHER", "SERVER", "1500", "OTHER"},
+		{"port in the server over --port", "SERVER,1433", "1500", "", "SERVER", "1433", ""},
+		{"instance in the server over --instance", `SERVER\INSTANCE`, "", "OTHER", "SERVER", "", "INSTANCE"},
+		{"instance in the server, with --port", `SERVER\INSTANCE`, "1500", "", "SERVER", "1500", "INSTANCE"},
+		{"port in the server, with --instance", "SERVER,1433", "", "OTHER", "SERVER", "1433", "OTHER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, instance, err := NormalizeServer(tt.server, tt.port, tt.instance)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if host != tt.host || port != tt.wantPort || instance != tt.wantIn {
+				t.Errorf("got '%s', '%s', '%s', want '%s', '%s', '%s'", host, port, instance, tt.host, tt.wantPort, tt.wantIn)
+			}
+		})
+	}
+
+	if _, _, _, err := NormalizeServer(`SERVER\,1433`, "", ""); err == nil {
+		t.Error("expected an error for an empty instance name")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Failures older than this no longer suggest the database is still resuming.
+const stateFailureWindow = time.Hour
+
+// What a run remembers for the next: recent failures that suggest the database is still cold or throttled,
+// and when the last run started connecting, for the --cooldown.
+type State struct {
+	LastFailure         time.Time `json:"last_failure"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastRun             time.Time `json:"last_run"`
+}
+
+// Read the state of the previous runs. A missing file is an empty state.
+func ReadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("error reading state file: %v", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error reading state file '%s': %v", path, err)
+	}
+	return state, nil
+}
+
+// Replace the state file atomically, so a simultaneous run never reads a partial state.
+func WriteState(path string, state State) error {
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(state)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
+
+// The pause before the first attempt: the retry delay, doubled for every recent consecutive failure after the first,
+// up to the maximum delay. Without a failure in the last hour, there is no pause.
+func (s State) InitialDelay(now time.Time, retry RetryConfig) time.Duration {
+	if s.ConsecutiveFailures < 1 || now.Sub(s.LastFailure) > stateFailureWindow {
+		return 0
+	}
+	delay := retry.RetryDelay
+	for range s.ConsecutiveFailures - 1 {
+		if delay >= retry.maxDelay() {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, retry.maxDelay())
+}
+
+// The time left of the cooldown after the last run, or zero when a run may connect.
+func (s State) Cooldown(now time.Time, cooldown time.Duration) time.Duration {
+	if s.LastRun.IsZero() {
+		return 0
+	}
+	return max(s.LastRun.Add(cooldown).Sub(now), 0)
+}
+
+// The state after a run: a run with a throttled or timed out result adds a failure, a successful run clears them.
+// Other failures, like a rejected login, say nothing about the database, so they keep the state.
+func (s State) Update(now time.Time, results []Result) State {
+	failures := s.ConsecutiveFailures
+	if now.Sub(s.LastFailure) > stateFailureWindow {
+		failures = 0
+	}
+
+	success := true
+	for _, r := range results {
+		if r.Throttled || r.TimedOut {
+			return State{LastFailure: now, ConsecutiveFailures: failures + 1, LastRun: s.LastRun}
+		}
+		success = success && r.Success
+	}
+	if success {
+		return State{LastRun: s.LastRun}
+	}
+	return s
+}
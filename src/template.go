@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"regexp"
+)
+
+var placeholder = regexp.MustCompile(`\{(env:)?([A-Za-z0-9_]+)\}`)
+
+// Expand {name} placeholders with values, and {env:NAME} with environment variable NAME.
+// Unknown placeholders are left as-is.
+func ExpandTemplate(template string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		groups := placeholder.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return os.Getenv(groups[2])
+		}
+		if value, ok := values[groups[2]]; ok {
+			return value
+		}
+		return match
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Where the time of a successful attempt went, with --timing, in milliseconds.
+// TLS is negotiated inside the TDS prelogin, so the handshake includes both.
+type Timing struct {
+	DNSMs       int64 `json:"dns_ms"`       // Until the TCP dial: mostly the lookup of the server name (and of a named instance)
+	DialMs      int64 `json:"dial_ms"`      // The TCP connection
+	HandshakeMs int64 `json:"handshake_ms"` // The TDS prelogin and the TLS handshake
+	LoginMs     int64 `json:"login_ms"`     // The login, which waits for the resume of a paused database
+	PingMs      int64 `json:"ping_ms"`
+	QueriesMs   int64 `json:"queries_ms"` // The USE, readiness and ping queries, and --verify-stable
+}
+
+func (t Timing) String() string {
+	return fmt.Sprintf("dns %v, dial %v, handshake %v, login %v, ping %v, queries %v",
+		ms(t.DNSMs), ms(t.DialMs), ms(t.HandshakeMs), ms(t.LoginMs), ms(t.PingMs), ms(t.QueriesMs))
+}
+
+func ms(n int64) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+// First bytes of the writes during a handshake: a TDS prelogin packet, which carries the TLS handshake unless
+// the encryption is strict, and the TLS change cipher spec and handshake records.
+const (
+	tdsPrelogin         = 0x12
+	tlsChangeCipherSpec = 0x14
+	tlsHandshake        = 0x16
+)
+
+// A dialer that records when the first TCP connection was dialed, and when its handshake ended:
+// at its first write that is not part of the handshake, which is the login.
+// UDP connections are not timed: those ask the SQL Browser for the port of a named instance.
+type timingDialer struct {
+	dialer mssql.Dialer
+
+	mu                               sync.Mutex
+	dialStart, dialEnd, handshakeEnd time.Time
+}
+
+// A timingDialer for a mssql.HostDialer, so the driver still leaves resolving the server name to it.
+type timingHostDialer struct {
+	*timingDialer
+	host string
+}
+
+func (d timingHostDialer) HostName() string {
+	return d.host
+}
+
+// Wrap a dialer, to time the first TCP connection it makes. The second value is the dialer for the connector.
+func newTimingDialer(dialer mssql.Dialer) (*timingDialer, mssql.Dialer) {
+	d := &timingDialer{dialer: dialer}
+	if hd, ok := dialer.(mssql.HostDialer); ok {
+		return d, timingHostDialer{d, hd.HostName()}
+	}
+	return d, d
+}
+
+func (d *timingDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(network, "udp") { // The SQL Browser, before the TDS connection
+		return conn, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.dialStart.IsZero() {
+		return conn, nil
+	}
+	d.dialStart, d.dialEnd = start, time.Now()
+	return &timingConn{Conn: conn, dialer: d}, nil
+}
+
+// The phases of the first connection, which started at start and was logged in at connected.
+func (d *timingDialer) timing(start, connected time.Time) Timing {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dialStart.IsZero() {
+		// Not dialed by this dialer, like with named pipes
+		return Timing{LoginMs: connected.Sub(start).Milliseconds()}
+	}
+
+	handshakeEnd := d.handshakeEnd
+	if handshakeEnd.IsZero() {
+		handshakeEnd = d.dialEnd
+	}
+	return Timing{
+		DNSMs:       d.dialStart.Sub(start).Milliseconds(),
+		DialMs:      d.dialEnd.Sub(d.dialStart).Milliseconds(),
+		HandshakeMs: handshakeEnd.Sub(d.dialEnd).Milliseconds(),
+		LoginMs:     connected.Sub(handshakeEnd).Milliseconds(),
+	}
+}
+
+type timingConn struct {
+	net.Conn
+	dialer *timingDialer
+	done   bool // Whether the handshake ended
+}
+
+func (c *timingConn) Write(b []byte) (int, error) {
+	if !c.done && len(b) > 0 && b[0] != tdsPrelogin && b[0] != tlsChangeCipherSpec && b[0] != tlsHandshake {
+		c.done = true
+		c.dialer.mu.Lock()
+		c.dialer.handshakeEnd = time.Now()
+		c.dialer.mu.Unlock()
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// A dialer that takes delay to dial each network, and returns one end of a pipe.
+type slowDialer map[string]time.Duration
+
+func (d slowDialer) DialContext(_ context.Context, network string, _ string) (net.Conn, error) {
+	time.Sleep(d[network])
+	conn, _ := net.Pipe()
+	return conn, nil
+}
+
+func TestTimingDialerSkipsUDP(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks []string
+	}{
+		{"TCP only", []string{"tcp"}},
+		{"SQL Browser before TCP", []string{"udp", "tcp"}},
+		{"SQL Browser over IPv4 before TCP", []string{"udp4", "tcp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td, dialer := newTimingDialer(slowDialer{"udp": 100 * time.Millisecond, "udp4": 100 * time.Millisecond, "tcp": 0})
+			start := time.Now()
+			for _, network := range tt.networks {
+				conn, err := dialer.DialContext(context.Background(), network, "myserver:1433")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				defer conn.Close()
+				if _, timed := conn.(*timingConn); timed != (network == "tcp") {
+					t.Errorf("got timed %v for network %s", timed, network)
+				}
+			}
+
+			timing := td.timing(start, time.Now())
+			if timing.DialMs >= 100 {
+				t.Errorf("got dial %v, want only the TCP connection", ms(timing.DialMs))
+			}
+		})
+	}
+}
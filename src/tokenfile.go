@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// How often a missing or expired token file is read again, while a login waits for it to be refreshed.
+const tokenFilePollInterval = 5 * time.Second
+
+// A credential that reads a Microsoft Entra access token from a file, which an external process keeps refreshed.
+// The file is read for every new connection, so a long-running process logs in with the current token.
+type TokenFileCredential struct {
+	path   string
+	logger *slog.Logger
+}
+
+func NewTokenFileCredential(path string, logger *slog.Logger) *TokenFileCredential {
+	return &TokenFileCredential{path: path, logger: logger}
+}
+
+// Read the token. While the file is missing, empty or holds an expired token, it is read again until ctx is done.
+// The scopes are ignored: the external process decides which token it writes.
+func (c *TokenFileCredential) GetToken(ctx context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	for warned := false; ; warned = true {
+		token, err := c.read()
+		if err == nil {
+			if warned {
+				c.logger.Info(fmt.Sprintf("Read a refreshed access token from '%s'.", c.path))
+			}
+			return token, nil
+		}
+		if !warned {
+			c.logger.Warn(fmt.Sprintf("%v, waiting for it to be refreshed", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return azcore.AccessToken{}, err
+		case <-time.After(tokenFilePollInterval):
+		}
+	}
+}
+
+func (c *TokenFileCredential) read() (azcore.AccessToken, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("error reading access token: %v", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return azcore.AccessToken{}, fmt.Errorf("token file '%s' is empty", c.path)
+	}
+
+	expiresOn, ok := tokenExpiry(token)
+	if ok && !time.Now().Before(expiresOn) {
+		return azcore.AccessToken{}, fmt.Errorf("the access token in '%s' expired at %s", c.path, expiresOn.Format(time.RFC3339))
+	}
+	return azcore.AccessToken{Token: token, ExpiresOn: expiresOn}, nil
+}
+
+// The expiry (exp claim) of a JWT access token. False for an opaque token, which is then used as-is.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
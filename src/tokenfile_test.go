@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// A JWT with only an exp claim, which is all that is read from it.
+func testJWT(exp time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + claims + ".c2lnbmF0dXJl"
+}
+
+func TestTokenFileCredential(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name      string
+		content   string
+		token     string
+		expiresOn time.Time
+	}{
+		{"opaque token", "opaque-token", "opaque-token", time.Time{}},
+		{"trailing newline", "opaque-token\n", "opaque-token", time.Time{}},
+		{"surrounding whitespace", "  \topaque-token \r\n", "opaque-token", time.Time{}},
+		{"JWT", testJWT(exp) + "\n", testJWT(exp), exp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := NewTokenFileCredential(path, discardLogger).GetToken(context.Background(), policy.TokenRequestOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token.Token != tt.token {
+				t.Errorf("got token '%s', want '%s'", token.Token, tt.token)
+			}
+			if !token.ExpiresOn.Equal(tt.expiresOn) {
+				t.Errorf("got expiry %v, want %v", token.ExpiresOn, tt.expiresOn)
+			}
+		})
+	}
+}
+
+func TestTokenFileCredentialUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	empty, expired := filepath.Join(dir, "empty"), filepath.Join(dir, "expired")
+	if err := os.WriteFile(empty, []byte(" \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(expired, []byte(testJWT(time.Now().Add(-time.Minute))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		err  string
+	}{
+		{"missing", filepath.Join(dir, "missing"), "error reading access token"},
+		{"empty", empty, "is empty"},
+		{"expired", expired, "expired at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The file is polled until the context is done, so it must not wait for a refresh
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+
+			_, err := NewTokenFileCredential(tt.path, discardLogger).GetToken(ctx, policy.TokenRequestOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want one with '%s'", err, tt.err)
+			}
+			if elapsed := time.Since(start); elapsed > tokenFilePollInterval {
+				t.Errorf("took %v, want it to end with the context", elapsed)
+			}
+		})
+	}
+}
+
+func TestTokenFileCredentialRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	credential := NewTokenFileCredential(path, discardLogger)
+	if token, err := credential.GetToken(context.Background(), policy.TokenRequestOptions{}); err != nil || token.Token != "first" {
+		t.Fatalf("got token '%s' and error %v, want 'first'", token.Token, err)
+	}
+
+	// The file is read for every login, so a refreshed token is used right away
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := credential.GetToken(context.Background(), policy.TokenRequestOptions{}); err != nil || token.Token != "second" {
+		t.Errorf("got token '%s' and error %v, want 'second'", token.Token, err)
+	}
+}
+
+func TestTokenFilePrintConnectionString(t *testing.T) {
+	const token = "eyJ0eXAiOiJKV1Qi.c2VjcmV0.c2ln"
+
+	// With --auth=token-file, the token is read when connecting, so it is never in the connection string
+	dsn, err := BuildDSN(ConnectionConfig{Server: "myserver", Database: "db", TokenAuth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := RedactConnectionString(dsn); strings.Contains(got, token) || strings.Contains(got, "@") {
+		t.Errorf("got '%s', want no token or user info", got)
+	}
+
+	// A token that was pasted into a DSN instead is masked
+	for _, dsn := range []string{
+		"server=myserver;database=db;access token=" + token,
+		"sqlserver://myserver?database=db&accesstoken=" + token,
+	} {
+		if got := RedactConnectionString(dsn); strings.Contains(got, "c2VjcmV0") {
+			t.Errorf("token leaked: %s", got)
+		}
+	}
+}